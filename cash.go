@@ -14,11 +14,19 @@ package cash
 
 import (
 	"bytes"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/gob"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -29,9 +37,53 @@ type Cash struct {
 	Currency   rune
 	Decimal    rune
 	Thousands  rune
+	Code       string     // ISO 4217, e.g. "USD"; empty if unknown
+	JSONFormat JSONFormat // how MarshalJSON renders this value
+	Exact      bool       // keep `Rational` across multiplications; see SetExactMode
+	Rounding   RoundingMode
+
+	// display only: show between Min and Max fractional digits,
+	// trimming trailing zeros; ignored unless MaxFracDigits > 0
+	MinFracDigits int
+	MaxFracDigits int
+
+	// display only: String() of a zero amount, e.g. "—"; unset is "$0.00"
+	ZeroPlaceholder string
+
+	// display only: where String() puts the symbol
+	// "$10.00" by default; SymbolAfter gives "10.00€", plus SymbolSpace "10.00 €"
+	SymbolAfter bool
+	SymbolSpace bool
+
+	// display only: how String() marks a negative amount
+	Negative NegativeStyle
 }
 
-var MinorUnit = []int64{1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000, 10000000000}
+// how String() writes a negative amount
+type NegativeStyle int
+
+const (
+	NegParens     NegativeStyle = iota // ($1.23), accounting style
+	NegMinus                           // -$1.23, for CSV and friends
+	NegMinusAfter                      // $1.23-, German accounting style
+)
+
+// selects what MarshalJSON emits
+type JSONFormat int
+
+const (
+	JSONDecimal    JSONFormat = iota // "$10,018.97"
+	JSONISOMinor                     // {"value": 1001897, "currency": "USD"}
+	JSONMinorUnits                   // 1001897; the scale is the receiver's FracDigits
+)
+
+// 10^n for every supported `FracDigits`; 18 (wei-style tokens) is the most
+// an int64 can hold, and leaves headroom for only ~9.2 whole units
+var MinorUnit = []int64{
+	1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000,
+	10000000000, 100000000000, 1000000000000, 10000000000000, 100000000000000,
+	1000000000000000, 10000000000000000, 100000000000000000, 1000000000000000000,
+}
 
 // presets
 var (
@@ -41,6 +93,7 @@ var (
 		Decimal:    '.',
 		Thousands:  ',',
 		Rational:   nil,
+		Code:       "USD",
 	}
 
 	EUR = Cash{
@@ -49,6 +102,25 @@ var (
 		Decimal:    '.',
 		Thousands:  ',',
 		Rational:   nil,
+		Code:       "EUR",
+	}
+
+	GBP = Cash{
+		Currency:   '£',
+		FracDigits: 2,
+		Decimal:    '.',
+		Thousands:  ',',
+		Rational:   nil,
+		Code:       "GBP",
+	}
+
+	JPY = Cash{
+		Currency:   '¥',
+		FracDigits: 0,
+		Decimal:    '.',
+		Thousands:  ',',
+		Rational:   nil,
+		Code:       "JPY",
 	}
 
 	BTC = Cash{
@@ -57,214 +129,1091 @@ var (
 		Decimal:    '.',
 		Thousands:  ',',
 		Rational:   nil,
+		Code:       "BTC",
+	}
+)
+
+// how a region writes money, independent of which currency it is
+type Locale struct {
+	Decimal     rune
+	Thousands   rune
+	SymbolAfter bool
+	SymbolSpace bool
+	FracDigits  int
+}
+
+// locales by BCP 47 tag; see SetLocale
+var Locales = map[string]Locale{
+	"en-US": {Decimal: '.', Thousands: ',', FracDigits: 2},                                       // $1,234.56
+	"de-DE": {Decimal: ',', Thousands: '.', SymbolAfter: true, SymbolSpace: true, FracDigits: 2}, // 1.234,56 €
+	"fr-FR": {Decimal: ',', Thousands: '\u00a0', SymbolAfter: true, SymbolSpace: true, FracDigits: 2},
+	"ja-JP": {Decimal: '.', Thousands: ',', FracDigits: 0}, // ¥1,235
+}
+
+// presets by ISO 4217 code
+var (
+	registry = map[string]Cash{
+		USD.Code: USD,
+		EUR.Code: EUR,
+		GBP.Code: GBP,
+		JPY.Code: JPY,
+		BTC.Code: BTC,
 	}
+	registryMu sync.RWMutex
 )
 
+// add (or replace) a preset in the registry under its `Code`
+// codes are three upper-case letters, ISO 4217 style
+func Register(preset Cash) error {
+	if preset.Code == "" {
+		return ErrNoCode
+	}
+	if len(preset.Code) != 3 || !isUpperCode(preset.Code) {
+		return ErrBadCode
+	}
+	preset.Amt = 0
+	preset.Rational = nil
+	registryMu.Lock()
+	registry[preset.Code] = preset
+	registryMu.Unlock()
+	return nil
+}
+
+// where ISO 4217 codes get resolved, for plugging in your own
+// currency data (DB-backed, config-driven, ...)
+// `symbol` must be a single rune (or empty for none), as that's all a
+// `Cash` can hold; a longer one like "CHF" counts as not found
+type CurrencyProvider interface {
+	Lookup(code string) (symbol string, fracDigits int, ok bool)
+}
+
+// the built-in registry, as a CurrencyProvider
+type registryProvider struct{}
+
+func (registryProvider) Lookup(code string) (string, int, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	preset, ok := registry[code]
+	return runeString(preset.Currency), preset.FracDigits, ok
+}
+
+var provider CurrencyProvider = registryProvider{}
+
+// resolve codes through `p` instead of the built-in registry
+// nil puts the built-in registry back
+func SetCurrencyProvider(p CurrencyProvider) {
+	if p == nil {
+		p = registryProvider{}
+	}
+	registryMu.Lock()
+	provider = p
+	registryMu.Unlock()
+}
+
+// preset for an ISO 4217 code
+// straight from the registry, unless a custom provider is set; then its
+// symbol and precision, with the registry's separators if it has them
+func lookupCode(code string) (Cash, bool) {
+	registryMu.RLock()
+	p := provider
+	preset, registered := registry[code]
+	registryMu.RUnlock()
+	if _, builtin := p.(registryProvider); builtin {
+		return preset, registered
+	}
+
+	symbol, fracDigits, ok := p.Lookup(code)
+	if !ok || utf8.RuneCountInString(symbol) > 1 {
+		return Cash{}, false // not truncated to "C" for "CHF"
+	}
+	if !registered {
+		preset = Cash{Decimal: '.', Thousands: ','}
+	}
+	preset.Currency, _ = utf8.DecodeRuneInString(symbol)
+	if symbol == "" {
+		preset.Currency = 0
+	}
+	preset.FracDigits = fracDigits
+	preset.Code = code
+	return preset, true
+}
+
+// zero amount in the currency with ISO 4217 `code`
+func NewFromCode(code string) (*Cash, error) {
+	preset, ok := lookupCode(code)
+	if !ok {
+		return nil, ErrUnknownCurrency
+	}
+	return New(preset), nil
+}
+
+// symbol, ISO code, and standard precision, e.g. for currency pickers
+// comes from the registry; unregistered currencies give back their own
+// symbol and precision with an empty code
+func (z *Cash) CurrencyInfo() (symbol string, code string, fracDigits int) {
+	if preset, ok := lookupCode(z.Code); ok {
+		return runeString(preset.Currency), preset.Code, preset.FracDigits
+	}
+	return runeString(z.Currency), "", z.FracDigits
+}
+
+// string(r), except the zero rune is ""
+func runeString(r rune) string {
+	if r == 0 {
+		return ""
+	}
+	return string(r)
+}
+
 func New(src Cash) *Cash {
 	ret := src
 	return &ret
 }
 
+// parse a plain decimal string without choosing a currency up front
+// `FracDigits` is however many fractional digits `s` actually has
+// (capped at the end of the MinorUnit table); no currency symbol
+func ParseInferred(s string) (*Cash, error) {
+	s = strings.TrimSpace(s)
+	fracDigits := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		fracDigits = utf8.RuneCountInString(s[i+1:])
+	}
+	if max := len(MinorUnit) - 1; fracDigits > max {
+		fracDigits = max
+	}
+	z := &Cash{
+		FracDigits: fracDigits,
+		Decimal:    '.',
+		Thousands:  ',',
+	}
+	return z.SetString(s)
+}
+
+// parse a form or query string value as `preset`'s currency
+// forgiving about what people type: "$1,234.56", " 1234.56 ", and an
+// empty field (zero) all work
+// a code typed in ("JPY 100000") has to be the preset's, or it's
+// ErrWrongCurrency
+func ParseFormValue(preset Cash, v string) (*Cash, error) {
+	z := New(preset)
+	z.Rational = nil
+	if v = strings.TrimSpace(v); v == "" {
+		return z.SetCents(0), nil
+	}
+	if _, err := z.SetString(v); err != nil {
+		return nil, err
+	}
+	if err := z.keepsCode(preset.Code); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// a code prefix in the input switches the format; where the caller
+// already knows the currency, that's an error rather than a conversion
+func (z *Cash) keepsCode(want string) error {
+	if want == "" {
+		return nil
+	}
+	return z.MustBeCurrency(want)
+}
+
 // convenience factory for $USD values
 func NewUSD() *Cash {
 	ret := USD
 	return &ret
 }
 
+// exactly half a minor unit of `preset`, e.g. 0.005 for 2 digits
+// test support: feed it to MulByRat and friends to hit rounding ties
+func HalfUnit(preset Cash) *big.Rat {
+	return big.NewRat(1, 2*preset.minorUnitFactor())
+}
+
 // gets 10^n where n = number of digits in mantissa
 func (z *Cash) minorUnitFactor() int64 {
 	return MinorUnit[z.FracDigits]
 }
 
 // sets the precision to the right of the decimal point (mantissa)
-// call before String() to get custom precision with proper rounding
-func (z *Cash) SetPrec(prec int) {
+// the amount is re-quantized to match, rounding per z.Rounding when going
+// coarser: $12.99 at 0 digits is $13, at 4 digits $12.9900
+// `prec` must be in [0, len(MinorUnit)); anything else is ErrBadScale
+// and leaves z alone
+func (z *Cash) SetPrec(prec int) (*Cash, error) {
+	if prec < 0 || prec >= len(MinorUnit) {
+		return nil, fmt.Errorf("SetPrec(%d): %w", prec, ErrBadScale)
+	}
+	var (
+		amt int64
+		err error
+	)
+	if z.rationalMatches() {
+		amt, err = ratToMinor(z.Rational, prec, z.Rounding)
+	} else {
+		amt, err = rescale(z.Amt, z.FracDigits, prec, z.Rounding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
 	z.FracDigits = prec
+	return z, nil
+}
+
+// z = x at `fracDigits`, rounded per z.Rounding; x is left alone
+// e.g., bring a 4-digit rate and a 2-digit price to the same precision
+// before Add: $1.2345 -> $1.23, $1.23 -> $1.2300
+func (z *Cash) Rescale(x *Cash, fracDigits int) (*Cash, error) {
+	c := *x
+	c.Rounding = z.Rounding
+	if _, err := c.SetPrec(fracDigits); err != nil {
+		return nil, err
+	}
+	return z.Set(&c), nil
+}
+
+// is there a retained exact value, and is `Amt` still its rounding?
+// if not, `Amt` was written since and is the one to trust
+func (z *Cash) rationalMatches() bool {
+	if z.Rational == nil {
+		return false
+	}
+	amt, err := ratToMinor(z.Rational, z.FracDigits, z.Rounding)
+	return err == nil && amt == z.Amt
+}
+
+// separators, symbol placement, and precision in one go
+// e.g., New(EUR).SetLocale(Locales["de-DE"]) renders "1.234,56 €"
+// the amount is rescaled like SetPrec if the precision changes
+func (z *Cash) SetLocale(l Locale) (*Cash, error) {
+	if _, err := z.SetPrec(l.FracDigits); err != nil {
+		return nil, err
+	}
+	z.Decimal = l.Decimal
+	z.Thousands = l.Thousands
+	z.SymbolAfter = l.SymbolAfter
+	z.SymbolSpace = l.SymbolSpace
+	return z, nil
+}
+
+// exact mode: multiplicative operations (MulByRat) keep the unrounded
+// result in `Rational` so a chain of them rounds only once, at Settle()
+// otherwise (the default) every operation rounds to `FracDigits` right away
+// and `Rational` stays nil
+// exact mode costs a big.Rat allocation per operation, and the numerator
+// and denominator keep growing down a long chain—settle when you're done
+func (z *Cash) SetExactMode(on bool) *Cash {
+	z.Exact = on
+	return z
+}
+
+// would restating at `toFracDigits` lose anything?
+// e.g., $1.00 -> 0 digits: no; $1.05 -> 0 digits: yes
+func (z *Cash) NeedsRounding(toFracDigits int) bool {
+	amt := z.Amt
+	for fd := z.FracDigits; fd > toFracDigits; fd-- {
+		if amt%10 != 0 {
+			return true
+		}
+		amt /= 10
+	}
+	return false
 }
 
 // can we do math between these two `Cash` instances?
+// separators are display only; a $1,234.56 and a $1.234,56 add up fine
 func (z *Cash) isCompatible(x *Cash) bool {
-	if z.FracDigits != x.FracDigits || z.Currency != x.Currency || z.Decimal != x.Decimal || z.Thousands != x.Thousands {
-		return false
+	return z.FracDigits == x.FracDigits && z.sameCurrency(x)
+}
+
+// the ISO code decides the currency when both sides have one ($ alone
+// could be USD, CAD, AUD...), otherwise the symbol does
+func (z *Cash) sameCurrency(x *Cash) bool {
+	if z.Code != "" && x.Code != "" {
+		return z.Code == x.Code
 	}
-	return true
+	return z.Currency == x.Currency
 }
 
-// rounds an integer half-to-even—like IEEE 754 does
-// strips "last," least significant digit (e.g., 3 in 123)
-// least significant digit determines direction of rounding
+// how to round when a value falls between two minor units
+// the zero value is banker's rounding
 // please: try to avoid rounding! this is money!
-func roundLikeBankers(x int64) int64 {
-	var (
-		leastSigDigit int64 = x % 10
-		mostSigDigits int64 = x / 10
-	)
+type RoundingMode int
+
+const (
+	RoundHalfEven   RoundingMode = iota // ties to the even neighbor—like IEEE 754 does
+	RoundHalfUp                         // ties away from zero
+	RoundHalfDown                       // ties toward zero
+	RoundCeil                           // toward +∞
+	RoundFloor                          // toward -∞
+	RoundTowardZero                     // truncate
+)
 
-	switch {
-	case leastSigDigit < 5:
-		return mostSigDigits
-	case leastSigDigit > 5:
-		return mostSigDigits + 1
-	case leastSigDigit == 5:
-		return mostSigDigits + (mostSigDigits & 1)
-	default:
-		// won't happen but compiler is stupid
-		return 0
+// should a truncated quotient move one step away from zero?
+// only asked when there is a remainder
+// half: how the remainder compares to half a step (-1, 0, +1)
+func (m RoundingMode) away(neg bool, half int, odd bool) bool {
+	switch m {
+	case RoundHalfUp:
+		return half >= 0
+	case RoundHalfDown:
+		return half > 0
+	case RoundCeil:
+		return !neg
+	case RoundFloor:
+		return neg
+	case RoundTowardZero:
+		return false
+	default: // RoundHalfEven
+		return half > 0 || (half == 0 && odd)
 	}
 }
 
+// what MarshalJSON emits and UnmarshalJSON expects for this value
+// e.g., New(USD).SetJSONFormat(JSONMinorUnits) for a JS client doing math
+func (z *Cash) SetJSONFormat(f JSONFormat) *Cash {
+	z.JSONFormat = f
+	return z
+}
+
+// rounding used when parsing, rescaling, and multiplying/dividing
+func (z *Cash) SetRoundingMode(m RoundingMode) *Cash {
+	z.Rounding = m
+	return z
+}
+
 // SetString() on already allocated `Cash`
+// understands an ISO 4217 code prefix ("USD 1.00"), accounting negatives
+// in parentheses, a leading or trailing minus ("-1.05", "1.234,56-"),
+// the currency symbol, and thousands separators
+// e.g., "USD (1,234.56)" or "(€1.234,56)" with European separators
+// on error `z` is left as it was
 func (z *Cash) SetString(src string) (*Cash, error) {
-	var neg bool = false
-	src = strings.Replace(src, "$", "", 1)
-	src = strings.Replace(src, ",", "", -1)
-	if strings.HasPrefix(src, "(") { // negative
-		z.Amt = z.Amt * -1
-		src = strings.Replace(src, "(", "", 1)
-		src = strings.Replace(src, ")", "", 1)
-		neg = true
+	// a code prefix switches the format, so work on a copy
+	w := *z
+	if _, err := w.setString(src); err != nil {
+		return nil, err
 	}
+	*z = w
+	return z, nil
+}
+
+func (z *Cash) setString(src string) (*Cash, error) {
 	var (
-		parts = strings.Split(src, string(z.Decimal))
+		neg   bool = false
+		coded bool // src had an ISO code prefix
 		err   error
 	)
-	switch len(parts) {
-	case 1: // just an integer
-		z.Amt, err = strconv.ParseInt(src, 10, 64)
-		if err != nil {
+	src = strings.TrimSpace(src)
+	if z.ZeroPlaceholder != "" && src == strings.TrimSpace(z.ZeroPlaceholder) {
+		return z.SetCents(0), nil // what String() shows for zero
+	}
+	if src, coded, err = z.stripCode(src); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(src, "(") && strings.HasSuffix(src, ")") { // negative
+		src = strings.TrimSpace(src[1 : len(src)-1])
+		neg = true
+	}
+	if !coded {
+		// code may sit inside the parentheses: "(USD 1,234.56)"
+		if src, coded, err = z.stripCode(src); err != nil {
 			return nil, err
 		}
-		if neg {
-			z.Amt = z.Amt * -1
+	}
+	minus := false
+	if strings.HasSuffix(src, "-") { // German accounting style
+		src, minus = strings.TrimSpace(src[:len(src)-1]), true
+	} else if strings.HasPrefix(src, "-") {
+		src, minus = strings.TrimSpace(src[1:]), true
+	}
+	if sym := string(z.Currency); z.Currency != 0 {
+		// either side, so "$ 10.00" and "10,00 €" both parse
+		if strings.HasPrefix(src, sym) {
+			src = strings.TrimSpace(src[len(sym):])
+		} else if strings.HasSuffix(src, sym) {
+			src = strings.TrimSpace(src[:len(src)-len(sym)])
+		}
+	}
+	if !minus && strings.HasPrefix(src, "-") { // "$-1.05"
+		src, minus = src[1:], true
+	}
+	if minus && neg || strings.HasPrefix(src, "-") {
+		return nil, ErrBadString // two signs
+	}
+	neg = neg || minus
+	amt, err := z.parseNumber(src, neg, z.Decimal, z.Thousands)
+	if err == ErrBadString && coded && z.Thousands != 0 {
+		// invoices write "EUR 12,34" whatever separators the preset uses;
+		// a number that only reads one way round is taken that way
+		if swapped, err2 := z.parseNumber(src, neg, z.Thousands, z.Decimal); err2 == nil {
+			amt, err = swapped, nil
 		}
-		return z, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// the unsigned number part of SetString, read with `dec` as the decimal
+// point and `thou` grouping the integer digits
+func (z *Cash) parseNumber(src string, neg bool, dec, thou rune) (int64, error) {
+	if thou != 0 && unicode.IsSpace(thou) {
+		// a no-break space for grouping is usually typed as a plain one
+		src = strings.Replace(src, " ", string(thou), -1)
+	}
+	var (
+		parts = strings.Split(src, string(dec))
+		ok    bool
+	)
+	switch len(parts) {
+	case 1: // just an integer, in major units: "5" is $5.00
+		parts = append(parts, "")
 	case 2: // decimal
-		integerPart, err := strconv.ParseInt(parts[0], 10, 64)
-		if err != nil {
-			return nil, err
+	default:
+		return 0, ErrBadString
+	}
+	if thou != 0 {
+		if parts[0], ok = ungroup(parts[0], thou); !ok {
+			return 0, ErrBadString
 		}
-		integerPart *= z.minorUnitFactor()
+	}
+	// forms let people type ".50" and "5."; "." alone is nothing
+	if parts[0] == "" && parts[1] == "" {
+		return 0, ErrBadString
+	}
+	if parts[0] == "" {
+		parts[0] = "0"
+	}
+	integerPart, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	// fitting in an int64 as whole units doesn't mean it fits as cents
+	if integerPart, err = mul64(integerPart, z.minorUnitFactor()); err != nil {
+		return 0, err
+	}
 
-		// sanitize fractional part
-		fracPartLen := utf8.RuneCountInString(parts[1])
-		if fracPartLen > z.FracDigits {
-			// just leave one extra digit for rounding
-			parts[1] = parts[1][:z.FracDigits+1]
+	// sanitize fractional part
+	if !isDigits(parts[1]) {
+		return 0, ErrBadString
+	}
+	fracLen := len(parts[1])
+	if fracLen > z.FracDigits {
+		// more digits than we keep: round the exact value
+		// the sign matters for ceil/floor, so apply it first
+		r, _ := new(big.Rat).SetString(parts[0] + "." + parts[1])
+		if neg {
+			r.Neg(r)
 		}
-		fracPart, err := strconv.ParseInt(parts[1], 10, 64)
-		if err != nil {
-			return nil, err
+		return ratToMinor(r, z.FracDigits, z.Rounding)
+	}
+	var fracPart int64
+	if fracLen > 0 {
+		if fracPart, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	// fewer digits than we keep: ".5" is 50 cents, not 5
+	fracPart *= MinorUnit[z.FracDigits-fracLen]
+	amt, err := add64(integerPart, fracPart)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		amt = amt * -1
+	}
+	return amt, nil
+}
+
+// drops the thousands separators from an integer part, checking they
+// group the way a person would: "1,234,567", not "1,2,3" or "12,34"
+func ungroup(s string, sep rune) (string, bool) {
+	groups := strings.Split(s, string(sep))
+	if len(groups) == 1 {
+		return s, true
+	}
+	for i, g := range groups {
+		if g == "" || len(g) > 3 || i > 0 && len(g) != 3 {
+			return "", false
 		}
-		if fracPartLen > z.FracDigits {
-			// handle rounding for mantissa
-			fracPart = roundLikeBankers(fracPart)
+	}
+	return strings.Join(groups, ""), true
+}
+
+// strips a leading ISO 4217 code like "USD " off `src`
+// a code other than the receiver's own switches it to that preset's format
+func (z *Cash) stripCode(src string) (string, bool, error) {
+	if len(src) < 3 || !isUpperCode(src[:3]) {
+		return src, false, nil
+	}
+	// must not be the start of a longer word
+	if len(src) > 3 && src[3] >= 'A' && src[3] <= 'Z' {
+		return src, false, nil
+	}
+	code := src[:3]
+	if code != z.Code {
+		preset, ok := lookupCode(code)
+		if !ok {
+			return src, false, ErrUnknownCurrency
 		}
-		z.Amt = integerPart + fracPart
-		if neg {
-			z.Amt = z.Amt * -1
+		z.Currency = preset.Currency
+		z.FracDigits = preset.FracDigits
+		z.Decimal = preset.Decimal
+		z.Thousands = preset.Thousands
+		z.Code = preset.Code
+	}
+	return strings.TrimSpace(src[3:]), true, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUpperCode(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
 		}
-		return z, nil
-	default:
-		return nil, ErrBadString
 	}
+	return true
 }
 
 // set the value of the minor unit
 // calling it cents just so you know what I mean
 func (z *Cash) SetCents(cents int64) *Cash {
 	z.Amt = cents
+	z.Rational = nil
 	return z
 }
 
-// String()
-func (z *Cash) String() string {
-	var (
-		buf         bytes.Buffer
-		integerPart string
-		fracPart    string
-		neg         bool
-	)
-
-	if z.IsPositive() != true {
-		neg=true
-		z.Amt = z.Amt * -1 // make positive
-		buf.WriteString("(")
+// set the value from an integer scaled by 10^scale
+// e.g., price feeds sending $1.23456 as 1234560 at scale 6
+// rescales to `FracDigits`, rounding if the feed is finer than we are
+func (z *Cash) SetScaledInt(v int64, scale int) (*Cash, error) {
+	amt, err := rescale(v, scale, z.FracDigits, z.Rounding)
+	if err != nil {
+		return nil, err
 	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
 
-	buf.WriteRune(z.Currency) // dollar sign
-	// decimal
-	decRaw := strconv.FormatInt(z.Amt, 10)
-	decRawLen := utf8.RuneCountInString(decRaw)
-
-	// is the int string too small? (that's what she said)
-	// outcome graph for the integer and fractional parts
+// moves an integer amount from one count of fractional digits to another
+// rounds per `mode` when going coarser
+func rescale(v int64, from, to int, mode RoundingMode) (int64, error) {
+	if from < 0 || from >= len(MinorUnit) || to < 0 || to >= len(MinorUnit) {
+		return 0, ErrBadScale
+	}
 	switch {
-	case decRawLen == 0:
-		// totally empty
-		return ""
-
-	case decRawLen == 1:
-		// [0, 9] cents—it's one digit
-		integerPart = "0"
-		fracPart = "0" + decRaw
-
-	case decRawLen == 2:
-		// only fractional, sans integer part
-		// 0.##
-		integerPart = "0"
-		fracPart = decRaw
-
-	case decRawLen > z.FracDigits:
-		// init integer part
-		integerPart = decRaw[:decRawLen-z.FracDigits]
-		// apply digit grouping on each thousands
-		integerPart = commafy(integerPart, z.Thousands)
-		// init fractional part
-		fracPart = decRaw[decRawLen-z.FracDigits:]
+	case from > to:
+		return roundQuo(v, MinorUnit[from-to], mode), nil
+	case from < to:
+		return mul64(v, MinorUnit[to-from])
+	default:
+		return v, nil
 	}
+}
 
-	// now build the overall string
-	buf.WriteString(integerPart) // write left side of decimal pt
-	buf.WriteRune(z.Decimal)     // decimal point
-	buf.WriteString(fracPart)    // write right side of decimal pt
-
-	if neg {
-		buf.WriteString(")")
-		z.Amt = z.Amt * -1 // make negative
+// divides n by d (d > 0), rounding per `mode`
+// symmetric around zero: -2.5 rounds like 2.5, mirrored
+func roundQuo(n, d int64, mode RoundingMode) int64 {
+	q, r := n/d, n%d
+	if r == 0 {
+		return q
 	}
+	if r < 0 {
+		r = -r
+	}
+	var half int
+	switch rest := d - r; { // distance to the next quotient away from zero
+	case r < rest:
+		half = -1
+	case r > rest:
+		half = 1
+	}
+	if !mode.away(n < 0, half, q&1 != 0) {
+		return q
+	}
+	if n < 0 {
+		return q - 1
+	}
+	return q + 1
+}
 
-	return buf.String()
+// multiplies without silently wrapping around
+func mul64(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	return c, nil
 }
 
-// commafy string of digits; digit grouping by thousands
-func commafy(s string, comma rune) string {
+// rational -> integer minor units at `fracDigits`, rounding per `mode`
+func ratToMinor(r *big.Rat, fracDigits int, mode RoundingMode) (int64, error) {
+	if fracDigits < 0 || fracDigits >= len(MinorUnit) {
+		return 0, ErrBadScale
+	}
 	var (
-		l   = utf8.RuneCountInString(s)
-		q   = l / 3
-		m   = l % 3
-		pos int
-		buf bytes.Buffer
+		n    = new(big.Int).Mul(r.Num(), big.NewInt(MinorUnit[fracDigits]))
+		m    = new(big.Int)
+		q, _ = new(big.Int).QuoRem(n, r.Denom(), m)
 	)
-	buf.WriteString(s[0:m])
-	for i := 0; i < q; i++ {
-		buf.WriteRune(comma)
-		pos = m + i
-		buf.WriteString(s[pos : pos+3])
+	if m.Sign() != 0 {
+		// compare the remainder against half the denominator
+		half := m.Abs(m).Lsh(m, 1).Cmp(r.Denom())
+		if mode.away(n.Sign() < 0, half, q.Bit(0) == 1) {
+			if n.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
 	}
-	return buf.String()
+	if !q.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return q.Int64(), nil
 }
 
-// TODO NewFromFloat64
-
-// NewFromBigRat
-func (z *Cash) NewFromBigRat(src *big.Rat) (*Cash, error) {
-	s := src.FloatString(z.FracDigits)
-	return z.SetString(s)
+// float64 -> integer minor units at `fracDigits`, rounding per `mode`
+// works on the float's exact binary value, so 9.999999 lands on 1000 cents
+func floatToMinor(f float64, fracDigits int, mode RoundingMode) (int64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrNotFinite
+	}
+	return ratToMinor(new(big.Rat).SetFloat64(f), fracDigits, mode)
 }
 
-// get big.Rat representation
-func (z *Cash) Rat() *big.Rat {
-	return big.NewRat(z.Amt, z.minorUnitFactor())
+// adds without silently wrapping around
+func add64(a, b int64) (int64, error) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, ErrOverflow
+	}
+	return c, nil
+}
+
+// subtracts without silently wrapping around
+func sub64(a, b int64) (int64, error) {
+	c := a - b
+	if (c < a) != (b > 0) {
+		return 0, ErrOverflow
+	}
+	return c, nil
+}
+
+// String()
+// read-only: works from a local copy of the amount, so concurrent
+// String() calls on a shared value are safe
+func (z *Cash) String() string {
+	return z.FormatWith(FormatOptions{Negative: z.Negative})
+}
+
+// per-call display choices for FormatWith
+// the zero value is String() with parentheses for negatives
+type FormatOptions struct {
+	NoSymbol      bool          // leave out the currency symbol
+	NoGrouping    bool          // leave out the thousands separators
+	Negative      NegativeStyle // ($1.23), -$1.23, or $1.23-
+	SymbolOutside bool          // symbol outside the sign: $(1.23), $-1.23
+	MinFracDigits int           // pad with zeros to at least this many
+	ISOCode       bool          // "USD 12.34" in place of the symbol; needs z.Code
+}
+
+// render with `opts` instead of the receiver's own display fields
+// separators, symbol placement, and ZeroPlaceholder still come from z
+// e.g., accounting columns: FormatWith(FormatOptions{SymbolOutside: true})
+// gives "$(1,234.56)"
+func (z *Cash) FormatWith(opts FormatOptions) string {
+	var (
+		buf         bytes.Buffer
+		integerPart string
+		fracPart    string
+		neg         bool
+	)
+
+	if z.Amt == 0 && z.ZeroPlaceholder != "" {
+		return z.ZeroPlaceholder
+	}
+
+	amt, fracDigits := z.displayFrac(z.Amt)
+	if opts.MinFracDigits > fracDigits && opts.MinFracDigits < len(MinorUnit) {
+		if padded, err := mul64(amt, MinorUnit[opts.MinFracDigits-fracDigits]); err == nil {
+			amt, fracDigits = padded, opts.MinFracDigits
+		}
+	}
+
+	code := opts.ISOCode && z.Code != ""
+	if code {
+		buf.WriteString(z.Code)
+		buf.WriteString(" ")
+	}
+	symbol := z.Currency != 0 && !opts.NoSymbol && !code
+	symbolBefore := func() {
+		if symbol && !z.SymbolAfter {
+			buf.WriteRune(z.Currency) // dollar sign
+			if z.SymbolSpace {
+				buf.WriteString(" ")
+			}
+		}
+	}
+	symbolAfter := func() {
+		if symbol && z.SymbolAfter {
+			if z.SymbolSpace {
+				buf.WriteString(" ")
+			}
+			buf.WriteRune(z.Currency) // euro sign, usually
+		}
+	}
+
+	if opts.SymbolOutside {
+		symbolBefore()
+	}
+	if amt < 0 {
+		neg = true
+		switch opts.Negative {
+		case NegMinus:
+			buf.WriteString("-")
+		case NegParens:
+			buf.WriteString("(")
+		}
+	}
+	if !opts.SymbolOutside {
+		symbolBefore()
+	}
+
+	// decimal, sans sign
+	decRaw := magnitude(amt)
+	decRawLen := utf8.RuneCountInString(decRaw)
+
+	// is the int string too small? (that's what she said)
+	// left-pad with zeros so there's always an integer digit
+	// works for any number of fractional digits, not just cents
+	if pad := fracDigits + 1 - decRawLen; pad > 0 {
+		decRaw = strings.Repeat("0", pad) + decRaw
+		decRawLen += pad
+	}
+	// init integer part
+	integerPart = decRaw[:decRawLen-fracDigits]
+	// apply digit grouping on each thousands
+	if z.Thousands != 0 && !opts.NoGrouping {
+		integerPart = commafy(integerPart, z.Thousands)
+	}
+	// init fractional part
+	fracPart = decRaw[decRawLen-fracDigits:]
+
+	// now build the overall string
+	buf.WriteString(integerPart) // write left side of decimal pt
+	if fracDigits > 0 {
+		buf.WriteRune(z.Decimal)  // decimal point
+		buf.WriteString(fracPart) // write right side of decimal pt
+	}
+
+	if !opts.SymbolOutside {
+		symbolAfter()
+	}
+	if neg {
+		switch opts.Negative {
+		case NegMinusAfter:
+			buf.WriteString("-")
+		case NegParens:
+			buf.WriteString(")")
+		}
+	}
+	if opts.SymbolOutside {
+		symbolAfter()
+	}
+
+	return buf.String()
+}
+
+// fmt.Formatter interface impl
+// %s, %v, %q, %x and %X are String() as they'd be for a string,
+// %d is the amount in minor units, and %f is a
+// plain decimal ("-1234.56") with the precision defaulting to FracDigits
+// and rounding per z.Rounding; width and the +, -, and 0 flags work
+// as they do for numbers
+func (z *Cash) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 's', 'v':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), z.String())
+	case 'q', 'x', 'X':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), z.String())
+	case 'd':
+		fmt.Fprintf(f, fmt.FormatString(f, 'd'), z.Amt)
+	case 'f':
+		prec, ok := f.Precision()
+		if !ok {
+			prec = z.FracDigits
+		}
+		var s string
+		if amt, err := rescale(z.Amt, z.FracDigits, prec, z.Rounding); err == nil {
+			c := Cash{Amt: amt, FracDigits: prec}
+			s = c.PlainString()
+		} else {
+			s = z.Rat().FloatString(prec)
+		}
+		if f.Flag('+') && z.Amt >= 0 {
+			s = "+" + s
+		}
+		padNumber(f, s)
+	default:
+		fmt.Fprintf(f, "%%!%c(cash=%s)", verb, z.String())
+	}
+}
+
+// writes a signed number string padded to the state's width
+func padNumber(f fmt.State, s string) {
+	w, ok := f.Width()
+	n := w - utf8.RuneCountInString(s)
+	if !ok || n <= 0 {
+		f.Write([]byte(s))
+		return
+	}
+	switch {
+	case f.Flag('-'):
+		s += strings.Repeat(" ", n)
+	case f.Flag('0'):
+		// zeros go between the sign and the digits
+		sign := ""
+		if s[0] == '-' || s[0] == '+' {
+			sign, s = s[:1], s[1:]
+		}
+		s = sign + strings.Repeat("0", n) + s
+	default:
+		s = strings.Repeat(" ", n) + s
+	}
+	f.Write([]byte(s))
+}
+
+// format then reparse each sample amount (in minor units)
+// a CI self-check for custom currency configurations;
+// describes the first sample that doesn't come back unchanged
+func (z *Cash) VerifyRoundTrip(sampleUnits []int64) error {
+	for _, u := range sampleUnits {
+		c := *z
+		c.Rational = nil
+		c.Amt = u
+		s := c.String()
+
+		back := *z
+		back.Rational = nil
+		back.Amt = 0
+		if _, err := back.SetString(s); err != nil {
+			return fmt.Errorf("%w: %d formats as %q, which doesn't parse: %v", ErrRoundTrip, u, s, err)
+		}
+		if back.Amt != u {
+			return fmt.Errorf("%w: %d formats as %q, which parses back as %d", ErrRoundTrip, u, s, back.Amt)
+		}
+	}
+	return nil
+}
+
+// String() without the currency symbol, e.g. "10,018.97" or "(10,018.97)"
+// for columns whose header already names the currency
+func (z *Cash) StringNoSymbol() string {
+	return z.FormatWith(FormatOptions{NoSymbol: true, Negative: z.Negative})
+}
+
+// render as a change: "+$1.50", "-$0.75", or plain "$0.00"
+// always an explicit sign, never parentheses
+func (z *Cash) StringDelta() string {
+	c := *z
+	c.ZeroPlaceholder = ""
+	// FormatWith writes the minus itself and never negates Amt, which
+	// would overflow for math.MinInt64
+	s := c.FormatWith(FormatOptions{Negative: NegMinus})
+	if z.Amt > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// unambiguous export form: plain decimal and ISO code, e.g. "1234.56 USD"
+// no symbol, no grouping, minus sign for negatives; zero-decimal
+// currencies have no point ("1000 JPY")
+func (z *Cash) StringWithCode() string {
+	s := z.PlainString()
+	if z.Code == "" {
+		return s
+	}
+	return s + " " + z.Code
+}
+
+// canonical decimal: '.' point, '-' sign, no symbol or grouping
+// e.g. "-1234.56", for CSV exports; ignores every display field, and
+// strconv.ParseFloat or big.Rat.SetString read it back
+func (z *Cash) PlainString() string {
+	var buf bytes.Buffer
+	if z.Amt < 0 {
+		buf.WriteString("-")
+	}
+	digits := magnitude(z.Amt)
+	if z.FracDigits <= 0 {
+		buf.WriteString(digits)
+		return buf.String()
+	}
+	// left-pad so there's always an integer digit
+	if pad := z.FracDigits + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	buf.WriteString(digits[:len(digits)-z.FracDigits])
+	buf.WriteString(".")
+	buf.WriteString(digits[len(digits)-z.FracDigits:])
+	return buf.String()
+}
+
+// how many fractional digits to show for an amount
+// with `MaxFracDigits` set, trailing zeros are trimmed down to
+// `MinFracDigits`, and anything past `MaxFracDigits` is rounded off
+// e.g., min=2, max=4: 1.5000 -> "1.50" but 1.5075 -> "1.5075"
+func (z *Cash) displayFrac(amt int64) (int64, int) {
+	fd := z.FracDigits
+	if z.MaxFracDigits <= 0 {
+		return amt, fd
+	}
+	if fd > z.MaxFracDigits {
+		amt = roundQuo(amt, MinorUnit[fd-z.MaxFracDigits], z.Rounding)
+		fd = z.MaxFracDigits
+	}
+	for fd > z.MinFracDigits && amt%10 == 0 {
+		amt /= 10
+		fd--
+	}
+	// past the table it couldn't fit anyway; like FormatWith, don't pad
+	if fd < z.MinFracDigits && z.MinFracDigits-fd < len(MinorUnit) {
+		if padded, err := mul64(amt, MinorUnit[z.MinFracDigits-fd]); err == nil {
+			amt, fd = padded, z.MinFracDigits
+		}
+	}
+	return amt, fd
+}
+
+// commafy string of digits; digit grouping by thousands
+// `s` must be ASCII digits only (no sign, no symbol), so byte offsets
+// are digit offsets; any length works, "" and "7" included
+func commafy(s string, comma rune) string {
+	var (
+		l   = len(s)
+		q   = l / 3
+		m   = l % 3
+		pos int
+		buf bytes.Buffer
+	)
+	buf.WriteString(s[0:m])
+	for i := 0; i < q; i++ {
+		if m > 0 || i > 0 {
+			buf.WriteRune(comma)
+		}
+		pos = m + 3*i
+		buf.WriteString(s[pos : pos+3])
+	}
+	return buf.String()
+}
+
+// render as a whole count of minor units plus a unit label
+// e.g., "12,345 sats" or "500 points"; ignores symbol and decimal point
+func (z *Cash) StringMinor(label string) string {
+	var buf bytes.Buffer
+	neg := z.Amt < 0
+	if neg {
+		buf.WriteString("(")
+	}
+	digits := magnitude(z.Amt)
+	if z.Thousands != 0 {
+		digits = commafy(digits, z.Thousands)
+	}
+	buf.WriteString(digits)
+	if label != "" {
+		buf.WriteString(" ")
+		buf.WriteString(label)
+	}
+	if neg {
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
+
+// decimal digits of |x|; safe for math.MinInt64
+func magnitude(x int64) string {
+	if x < 0 {
+		return strconv.FormatUint(uint64(-(x+1))+1, 10)
+	}
+	return strconv.FormatInt(x, 10)
+}
+
+// NewFromFloat64
+// lossy by nature: f is taken at its exact binary value and rounded to
+// FracDigits per z.Rounding, so 0.1+0.2 still comes out as 0.30
+func (z *Cash) NewFromFloat64(f float64) (*Cash, error) {
+	amt, err := floatToMinor(f, z.FracDigits, z.Rounding)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// NewFromBigRat
+func (z *Cash) NewFromBigRat(src *big.Rat) (*Cash, error) {
+	s := src.FloatString(z.FracDigits)
+	return z.SetString(s)
+}
+
+// set from an exact ratio of major units, e.g. "3 for $5" -> 5/3 -> $1.67
+// see Residual() for what the rounding threw away
+func (z *Cash) SetFromFraction(numerator, denominator int64) (*Cash, error) {
+	if denominator == 0 {
+		return nil, ErrDivideByZero
+	}
+	amt, err := ratToMinor(big.NewRat(numerator, denominator), z.FracDigits, z.Rounding)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// what rounding lost: exact - z
+// e.g., 5/3 - $1.67 == -1/300
+func (z *Cash) Residual(exact *big.Rat) *big.Rat {
+	return new(big.Rat).Sub(exact, z.Rat())
+}
+
+// get big.Rat representation
+func (z *Cash) Rat() *big.Rat {
+	return big.NewRat(z.Amt, z.minorUnitFactor())
+}
+
+// get float64 representation, e.g. for charting
+// not for arithmetic: most decimal amounts have no exact float64, and
+// past 2^53 minor units even the integer part starts losing digits
+func (z *Cash) Float64() float64 {
+	return float64(z.Amt) / float64(z.minorUnitFactor())
+}
+
+// exact decimal string with `prec` fractional digits, rounded half
+// away from zero as big.Rat.FloatString does
+func (z *Cash) Float64String(prec int) string {
+	return z.Rat().FloatString(prec)
 }
 
 // addition
@@ -272,176 +1221,1307 @@ func (z *Cash) Add(x, y *Cash) (*Cash, error) {
 	if !z.isCompatible(x) || !z.isCompatible(y) {
 		return nil, ErrIncompatible
 	}
-	z.Amt = x.Amt + y.Amt
+	amt, err := add64(x.Amt, y.Amt)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// x plus a raw count of minor units, e.g. a 99-cent fee
+func (z *Cash) AddCents(x *Cash, cents int64) (*Cash, error) {
+	if !z.isCompatible(x) {
+		return nil, ErrIncompatible
+	}
+	amt, err := add64(x.Amt, cents)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// x minus a raw count of minor units
+func (z *Cash) SubCents(x *Cash, cents int64) (*Cash, error) {
+	if !z.isCompatible(x) {
+		return nil, ErrIncompatible
+	}
+	amt, err := sub64(x.Amt, cents)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// total of the arguments, as a new `Cash` in their shared format
+// compatibility is checked once up front, then it's a plain int64 loop
+// no arguments means no format to give the total, so that's ErrEmpty
+func Sum(xs ...*Cash) (*Cash, error) {
+	if len(xs) == 0 {
+		return nil, ErrEmpty
+	}
+	for _, x := range xs[1:] {
+		if !xs[0].isCompatible(x) {
+			return nil, ErrIncompatible
+		}
+	}
+	z := fresh(xs[0])
+	for _, x := range xs {
+		var err error
+		if z.Amt, err = add64(z.Amt, x.Amt); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
+
+// total of same-currency values recorded at different precisions
+// everything is promoted to the finest `FracDigits` present
+// e.g., $1.00 (2 digits) + $0.0025 (4 digits) == $1.0025
+func SumPromote(values []Cash) (*Cash, error) {
+	if len(values) == 0 {
+		return nil, ErrEmpty
+	}
+	z := values[0]
+	z.Rational = nil
+	for i := range values {
+		if !values[i].sameCurrency(&z) {
+			return nil, ErrIncompatible
+		}
+		// pick up a code so a later CAD can't slip past a code-less $
+		if z.Code == "" {
+			z.Code = values[i].Code
+		}
+		if values[i].FracDigits > z.FracDigits {
+			z.FracDigits = values[i].FracDigits
+		}
+	}
+
+	z.Amt = 0
+	for i := range values {
+		amt, err := rescale(values[i].Amt, values[i].FracDigits, z.FracDigits, z.Rounding)
+		if err != nil {
+			return nil, err
+		}
+		if z.Amt, err = add64(z.Amt, amt); err != nil {
+			return nil, err
+		}
+	}
+	return &z, nil
+}
+
+// unique amounts, first-seen order kept, e.g. for price lists
+// everything must be in the same currency
+func Dedupe(values []Cash) ([]Cash, error) {
+	var (
+		ret  = make([]Cash, 0, len(values))
+		seen = make(map[int64]bool, len(values))
+	)
+	for i := range values {
+		if !values[0].isCompatible(&values[i]) {
+			return nil, ErrIncompatible
+		}
+		if seen[values[i].Amt] {
+			continue
+		}
+		seen[values[i].Amt] = true
+		ret = append(ret, values[i])
+	}
+	return ret, nil
+}
+
+// smallest of a slice by amount, e.g. the cheapest line item
+// points into `values` rather than copying
+func MinOf(values []Cash) (*Cash, error) {
+	return extremeOf(len(values), func(i int) *Cash { return &values[i] }, Less)
+}
+
+// largest of a slice by amount
+// points into `values` rather than copying
+func MaxOf(values []Cash) (*Cash, error) {
+	return extremeOf(len(values), func(i int) *Cash { return &values[i] }, Greater)
+}
+
+// smallest of the arguments, e.g. clamping a fee to its ceiling:
+// Min(fee, ceiling)
+// returns one of the arguments, not a copy
+func Min(xs ...*Cash) (*Cash, error) {
+	return extremeOf(len(xs), func(i int) *Cash { return xs[i] }, Less)
+}
+
+// largest of the arguments, e.g. Max(fee, floor)
+// returns one of the arguments, not a copy
+func Max(xs ...*Cash) (*Cash, error) {
+	return extremeOf(len(xs), func(i int) *Cash { return xs[i] }, Greater)
+}
+
+// whichever of the `n` values every other one is `want` of
+func extremeOf(n int, at func(int) *Cash, want Ordering) (*Cash, error) {
+	if n == 0 {
+		return nil, ErrEmpty
+	}
+	ret := at(0)
+	for i := 1; i < n; i++ {
+		o, err := at(i).Order(ret)
+		if err != nil {
+			return nil, err
+		}
+		if o == want {
+			ret = at(i)
+		}
+	}
+	return ret, nil
+}
+
+// subtraction
+func (z *Cash) Sub(x, y *Cash) (*Cash, error) {
+	if !z.isCompatible(x) || !z.isCompatible(y) {
+		return nil, ErrIncompatible
+	}
+	amt, err := sub64(x.Amt, y.Amt)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// absolute difference |x - y|, for variance reporting
+func (z *Cash) AbsDiff(x, y *Cash) (*Cash, error) {
+	if !z.isCompatible(x) || !z.isCompatible(y) {
+		return nil, ErrIncompatible
+	}
+	// difference in uint64 so it can't wrap, then see if it fits back
+	var d uint64
+	if x.Amt >= y.Amt {
+		d = uint64(x.Amt) - uint64(y.Amt)
+	} else {
+		d = uint64(y.Amt) - uint64(x.Amt)
+	}
+	if d > math.MaxInt64 {
+		return nil, ErrOverflow
+	}
+	z.Amt = int64(d)
+	z.Rational = nil
+	return z, nil
+}
+
+// negation: sets z to -x, format and all
+// like math/big, x is left alone and z can be x
+// note: -math.MinInt64 doesn't fit; it comes back unchanged
+func (z *Cash) Neg(x *Cash) *Cash {
+	r := x.Rational
+	*z = *x
+	z.Amt = -x.Amt
+	if r != nil {
+		z.Rational = new(big.Rat).Neg(r)
+	}
+	return z
+}
+
+// absolute value: sets z to |x|, format and all
+// |math.MinInt64| doesn't fit in int64, so that's ErrOverflow
+func (z *Cash) Abs(x *Cash) (*Cash, error) {
+	if x.Amt == math.MinInt64 {
+		return nil, ErrOverflow
+	}
+	if x.Amt < 0 {
+		return z.Neg(x), nil
+	}
+	r := x.Rational
+	*z = *x
+	if r != nil {
+		z.Rational = new(big.Rat).Set(r)
+	}
 	return z, nil
 }
 
-// subtraction
-func (z *Cash) Sub(x, y *Cash) (*Cash, error) {
-	if !z.isCompatible(x) || !z.isCompatible(y) {
-		return nil, ErrIncompatible
+// immutable variants
+// these never write to their inputs and always return a brand-new `Cash`
+// (format copied from x), so amounts can be shared between goroutines
+// as read-only values
+
+// x + y as a new value
+func Add(x, y *Cash) (*Cash, error) {
+	return fresh(x).Add(x, y)
+}
+
+// x - y as a new value
+func Sub(x, y *Cash) (*Cash, error) {
+	return fresh(x).Sub(x, y)
+}
+
+// -x as a new value
+func Neg(x *Cash) (*Cash, error) {
+	if x.Amt == math.MinInt64 {
+		return nil, ErrOverflow
+	}
+	return fresh(x).SetCents(-x.Amt), nil
+}
+
+// x * scalar as a new value
+func MulByScalar(x *Cash, scalar int64) (*Cash, error) {
+	return fresh(x).MulByScalar(x, scalar)
+}
+
+// z = x, like big.Int.Set; returns z
+// copies every field, reusing z's `Rational` for the deep copy if it
+// has one, so a receiver recycled in a loop doesn't allocate
+func (z *Cash) Set(x *Cash) *Cash {
+	if z == x {
+		return z
+	}
+	r := z.Rational
+	*z = *x
+	if x.Rational == nil {
+		z.Rational = nil
+		return z
+	}
+	if r == nil {
+		r = new(big.Rat)
+	}
+	z.Rational = r.Set(x.Rational)
+	return z
+}
+
+// deep copy; a plain `*z` copy would share the `Rational` big.Rat
+func (z *Cash) Clone() *Cash {
+	c := *z
+	if z.Rational != nil {
+		c.Rational = new(big.Rat).Set(z.Rational)
+	}
+	return &c
+}
+
+// new `Cash` with x's format and no amount or `Rational`
+func fresh(x *Cash) *Cash {
+	z := *x
+	z.Amt = 0
+	z.Rational = nil
+	return &z
+}
+
+// multiply `Cash` with a scalar value
+// e.g., $18.18 * 5
+// most realistic use case of multiplication for `Cash`
+func (z *Cash) MulByScalar(x *Cash, scalar int64) (*Cash, error) {
+	if !z.isCompatible(x) {
+		return nil, ErrIncompatible
+	}
+	amt, err := mul64(x.Amt, scalar)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+	z.Rational = nil
+	return z, nil
+}
+
+// `pct` percent of `Cash`, rounded per z.Rounding
+// e.g., 15% of price: New(USD).Percent(price, big.NewRat(15, 1))
+// fractional rates are fine: 8.875% is big.NewRat(8875, 1000)
+func (z *Cash) Percent(x *Cash, pct *big.Rat) (*Cash, error) {
+	return z.MulByRat(x, new(big.Rat).Quo(pct, big.NewRat(100, 1)))
+}
+
+// `bps` basis points (1/100 of a percent) of `Cash`, rounded per z.Rounding
+// plain int64 math, no big.Rat, unless x*bps would overflow or an exact
+// value is involved—for fee rows by the million
+func (z *Cash) MulByBasisPoints(x *Cash, bps int64) (*Cash, error) {
+	if !z.isCompatible(x) {
+		return nil, ErrIncompatible
+	}
+	n, err := mul64(x.Amt, bps)
+	if err != nil || x.Rational != nil || z.Exact {
+		return z.MulByRat(x, big.NewRat(bps, 10000))
+	}
+	z.Amt = roundQuo(n, 10000, z.Rounding)
+	z.Rational = nil
+	return z, nil
+}
+
+// multiply `Cash` with a rational number
+// under the hood: math/big.Rat
+// has mathematical accuracy
+// good for consecutive mul (or div) operations when in exact mode
+func (z *Cash) MulByRat(x *Cash, p *big.Rat) (*Cash, error) {
+	if !z.isCompatible(x) {
+		return nil, ErrIncompatible
+	}
+
+	// turn integer cents to a rational number
+	var xR *big.Rat
+	if x.Rational == nil {
+		xR = big.NewRat(x.Amt, x.minorUnitFactor())
+	} else {
+		xR = x.Rational
+	}
+
+	// multiply fractions
+	r := new(big.Rat).Mul(xR, p)
+
+	// retrieve integer cents
+	amt, err := ratToMinor(r, z.FracDigits, z.Rounding)
+	if err != nil {
+		return nil, err
+	}
+	z.Amt = amt
+
+	// only hang on to the exact value if asked to
+	if z.Exact {
+		z.Rational = r
+	} else {
+		z.Rational = nil
+	}
+
+	return z, nil
+}
+
+// apply a series of period-over-period changes in order
+// pcts are fractions (1/10 is +10%); the product of (1+pct) is exact,
+// so the result is rounded just once
+func (z *Cash) ApplyChanges(start *Cash, pcts []*big.Rat) (*Cash, error) {
+	var (
+		one     = big.NewRat(1, 1)
+		product = big.NewRat(1, 1)
+		f       = new(big.Rat)
+	)
+	for _, pct := range pcts {
+		product.Mul(product, f.Add(one, pct))
+	}
+	return z.MulByRat(start, product)
+}
+
+// multiply by a rate that arrived as a decimal string, e.g. "0.0825"
+// parsed exactly (no float64 on the way), then same as MulByRat
+func (z *Cash) MulByDecimalString(x *Cash, rate string) (*Cash, error) {
+	p, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok {
+		return nil, ErrBadString
+	}
+	return z.MulByRat(x, p)
+}
+
+// prorate by day count for partial subscription periods
+// returns a new `Cash` worth z * daysUsed/daysInPeriod, z is untouched
+func (z *Cash) Prorate(daysUsed, daysInPeriod int) (*Cash, error) {
+	if daysInPeriod <= 0 || daysUsed < 0 || daysUsed > daysInPeriod {
+		return nil, ErrBadDays
+	}
+	r := new(big.Rat).Mul(z.Rat(), big.NewRat(int64(daysUsed), int64(daysInPeriod)))
+	amt, err := ratToMinor(r, z.FracDigits, z.Rounding)
+	if err != nil {
+		return nil, err
+	}
+	ret := *z
+	ret.Rational = nil
+	ret.Amt = amt
+	return &ret, nil
+}
+
+// settle a high-precision intermediate to the currency's `FracDigits`
+// rounds the retained `Rational` (if any) into `Amt` and drops it
+// on the off chance it doesn't fit in int64, `Amt` is left alone
+func (z *Cash) Settle() *Cash {
+	if z.Rational == nil {
+		return z
+	}
+	if amt, err := ratToMinor(z.Rational, z.FracDigits, z.Rounding); err == nil {
+		z.Amt = amt
+	}
+	z.Rational = nil
+	return z
+}
+
+// x in another currency at a caller-supplied rate (units of `to` per
+// unit of x), e.g. $100.00 at 0.92 -> €92,00
+// z takes `to`'s format wholesale, and the result is rounded to its
+// `FracDigits` per its rounding mode
+func (z *Cash) Convert(x *Cash, to Cash, rate *big.Rat) (*Cash, error) {
+	if rate == nil || rate.Sign() < 0 {
+		return nil, ErrBadRate
+	}
+	xR := x.Rational
+	if xR == nil {
+		xR = x.Rat()
+	}
+	r := new(big.Rat).Mul(xR, rate)
+	amt, err := ratToMinor(r, to.FracDigits, to.Rounding)
+	if err != nil {
+		return nil, err
+	}
+	*z = to
+	z.Amt = amt
+	if z.Exact {
+		z.Rational = r
+	} else {
+		z.Rational = nil
+	}
+	return z, nil
+}
+
+// growth rate implied by two amounts: to/from - 1
+// e.g., $100.00 -> $140.00 is 2/5 (a 40% markup)
+func ImpliedRate(from, to *Cash) (*big.Rat, error) {
+	if !from.isCompatible(to) {
+		return nil, ErrIncompatible
+	}
+	if from.Amt == 0 {
+		return nil, ErrDivideByZero
+	}
+	r := new(big.Rat).SetFrac(big.NewInt(to.Amt), big.NewInt(from.Amt))
+	return r.Sub(r, big.NewRat(1, 1)), nil
+}
+
+// clamp z to within ±pct of `reference`, e.g. a price guard
+// the band is [reference*(1-pct), reference*(1+pct)], and the clamped
+// value is rounded inward so it never lands outside
+func (z *Cash) ClampToBand(reference *Cash, pct *big.Rat) (*Cash, error) {
+	if !z.isCompatible(reference) {
+		return nil, ErrIncompatible
+	}
+	if pct == nil || pct.Sign() < 0 {
+		return nil, ErrBadRate
+	}
+	one := big.NewRat(1, 1)
+	lo := new(big.Rat).Mul(reference.Rat(), new(big.Rat).Sub(one, pct))
+	hi := new(big.Rat).Mul(reference.Rat(), new(big.Rat).Add(one, pct))
+	if lo.Cmp(hi) > 0 { // negative reference
+		lo, hi = hi, lo
+	}
+
+	v := z.Rat()
+	switch {
+	case v.Cmp(hi) > 0:
+		amt, err := ratToMinor(hi, z.FracDigits, RoundFloor)
+		if err != nil {
+			return nil, err
+		}
+		z.Amt = amt
+	case v.Cmp(lo) < 0:
+		amt, err := ratToMinor(lo, z.FracDigits, RoundCeil)
+		if err != nil {
+			return nil, err
+		}
+		z.Amt = amt
+	}
+	z.Rational = nil
+	return z, nil
+}
+
+// charm pricing: move to the nearest amount whose fractional part is
+// one of `endings` (in minor units), e.g. $10.37 with {95, 99} -> $9.99
+// may cross into the next or previous whole unit; ties go up
+func (z *Cash) SnapToEndings(endings []int64) (*Cash, error) {
+	if len(endings) == 0 {
+		return nil, ErrEmpty
+	}
+	f := z.minorUnitFactor()
+	whole := z.Amt / f
+	if z.Amt%f < 0 {
+		whole-- // floor, so the fraction is always non-negative
+	}
+
+	var (
+		best     int64
+		bestDist uint64 = math.MaxUint64
+	)
+	for _, e := range endings {
+		if e < 0 || e >= f {
+			return nil, ErrBadEnding
+		}
+		for w := whole - 1; w <= whole+1; w++ {
+			c := w*f + e
+			var d uint64
+			if c >= z.Amt {
+				d = uint64(c - z.Amt)
+			} else {
+				d = uint64(z.Amt - c)
+			}
+			if d < bestDist || (d == bestDist && c > best) {
+				best, bestDist = c, d
+			}
+		}
+	}
+	z.Amt = best
+	z.Rational = nil
+	return z, nil
+}
+
+// multiplying two `Cash` money values
+// seems unlikely to be used at all
+// this is only here because it would look stupid if it weren't here
+func (z *Cash) MulByCash(x, y *Cash) (*Cash, error) {
+	if !z.isCompatible(x) || !z.isCompatible(y) {
+		return nil, ErrIncompatible
+	}
+	// x*y is in minor units squared and can overflow int64 even when
+	// the result, scaled back down, fits
+	p := new(big.Int).Mul(big.NewInt(x.Amt), big.NewInt(y.Amt))
+	p.Quo(p, big.NewInt(z.minorUnitFactor()))
+	if !p.IsInt64() {
+		return nil, ErrOverflow
+	}
+	z.Amt = p.Int64()
+	z.Rational = nil
+	return z, nil
+}
+
+// divide `Cash` by a scalar integer N
+// return a slice of N respective `Cash` values
+// inspired by Martin Fowler's "allocate"
+func (z *Cash) DivByScalar(y int64) ([]Cash, error) {
+	if y == 0 {
+		return nil, fmt.Errorf("DivByScalar(0): %w: %w", ErrDivideByZero, ErrNonPositiveDivisor)
+	}
+	if y < 0 {
+		return nil, fmt.Errorf("DivByScalar(%d): %w", y, ErrNonPositiveDivisor)
+	}
+	var (
+		i      int64
+		minima int64  = z.Amt / y
+		mod    int64  = z.Amt % y
+		step   int64  = 1
+		ret    []Cash = make([]Cash, y) // guarantee: y > |mod|
+	)
+	// a negative total truncates toward zero, so the leftover
+	// is negative too and the first shares get a unit more of debt
+	if mod < 0 {
+		step, mod = -1, -mod
+	}
+	maxima := minima + step
+
+	// first, assign maxima to res
+	// because sum(maxima - minima) over [0, mod) is less than 1
+	// each share is a copy of z's format, so z itself is left alone
+	for i = 0; i < mod; i++ {
+		ret[i] = *fresh(z).SetCents(maxima)
+	}
+
+	// then, assign minima to leftovers in res
+	for i = mod; i < y; i++ {
+		ret[i] = *fresh(z).SetCents(minima)
+	}
+
+	return ret, nil
+}
+
+// divide `Cash` by N without building the shares
+// quotient*n + remainder == x; the quotient is floored so the remainder
+// is always in [0, n), in minor units, and how the leftover gets handed
+// out is up to the caller
+func (z *Cash) DivMod(x *Cash, n int64) (*Cash, int64, error) {
+	if n == 0 {
+		return nil, 0, fmt.Errorf("DivMod(0): %w: %w", ErrDivideByZero, ErrNonPositiveDivisor)
+	}
+	if n < 0 {
+		return nil, 0, fmt.Errorf("DivMod(%d): %w", n, ErrNonPositiveDivisor)
+	}
+	if !z.isCompatible(x) {
+		return nil, 0, ErrIncompatible
+	}
+	q, r := x.Amt/n, x.Amt%n
+	if r < 0 {
+		q, r = q-1, r+n
+	}
+	z.Amt = q
+	z.Rational = nil
+	return z, r, nil
+}
+
+// divide `Cash` according to a set of numbers representing a ratio
+// return a slice of `Cash` values as long as the set (ratio)
+// inspired by Martin Fowler's "allocate"
+// `ratio` must be non-empty with no negative parts and a positive
+// total, or it's ErrBadRatio
+func (z *Cash) DivIntoRatio(ratio []int64) ([]Cash, error) {
+	return z.DivIntoRatioFrom(ratio, 0)
+}
+
+// like DivIntoRatio, but the leftover minor units go one each starting
+// at `start` and wrapping around, instead of from the first share
+// e.g., pass the period number to rotate who gets the extra cent
+func (z *Cash) DivIntoRatioFrom(ratio []int64, start int) ([]Cash, error) {
+	amts, err := allocateFrom(z.Amt, ratio, start)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Cash, len(amts))
+	for i, amt := range amts {
+		ret[i] = *fresh(z).SetCents(amt)
+	}
+	return ret, nil
+}
+
+// distribute `target` across `ratio`, conserving `target` exactly
+// like DivIntoRatio, but the total comes from `target` alone,
+// e.g., spreading an already-rounded grand total over line items
+func AllocateTo(target *Cash, ratio []int64) ([]Cash, error) {
+	amts, err := allocate(target.Amt, ratio)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Cash, len(amts))
+	for i, amt := range amts {
+		ret[i] = *target
+		ret[i].Rational = nil
+		ret[i].SetCents(amt)
+	}
+	return ret, nil
+}
+
+// split `Cash` among named parties by integer shares, conserving the total
+// leftover minor units go to names in sorted order, so results are stable
+func (z *Cash) AllocateNamed(shares map[string]int64) (map[string]Cash, error) {
+	names := make([]string, 0, len(shares))
+	for name := range shares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ratio := make([]int64, len(names))
+	for i, name := range names {
+		ratio[i] = shares[name]
+	}
+	amts, err := allocate(z.Amt, ratio)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]Cash, len(names))
+	for i, name := range names {
+		c := *z
+		c.Rational = nil
+		ret[name] = *c.SetCents(amts[i])
+	}
+	return ret, nil
+}
+
+// splits `amt` by `ratio` so the parts sum to exactly `amt`
+// leftover minor units go one each to the earliest parts,
+// in the same direction as `amt`
+func allocate(amt int64, ratio []int64) ([]int64, error) {
+	return allocateFrom(amt, ratio, 0)
+}
+
+// allocate, handing out the leftover from part `start` onward, wrapping
+func allocateFrom(amt int64, ratio []int64, start int) ([]int64, error) {
+	var denominator int64
+	for _, r := range ratio {
+		if r < 0 {
+			return nil, ErrBadRatio
+		}
+		var err error
+		if denominator, err = add64(denominator, r); err != nil {
+			return nil, err
+		}
+	}
+	if denominator == 0 {
+		return nil, ErrBadRatio
+	}
+
+	var (
+		ret = make([]int64, len(ratio))
+		mod = amt // start with whole; before subtracting
+		t   = new(big.Int)
+		den = big.NewInt(denominator)
+	)
+	for i, r := range ratio {
+		// amt * r can overflow int64 even though the share can't
+		t.Mul(big.NewInt(amt), big.NewInt(r)).Quo(t, den)
+		ret[i] = t.Int64()
+		mod -= ret[i]
+	}
+
+	// |mod| < len(ratio) from truncating each share toward zero
+	step := int64(1)
+	if mod < 0 {
+		step, mod = -1, -mod
 	}
-	z.Amt = x.Amt - y.Amt
-	return z, nil
+	l := len(ratio)
+	if start %= l; start < 0 {
+		start += l
+	}
+	for i := int64(0); i < mod; i++ {
+		ret[(start+int(i))%l] += step
+	}
+	return ret, nil
 }
 
-// multiply `Cash` with a scalar value
-// e.g., $18.18 * 5
-// most realistic use case of multiplication for `Cash`
-func (z *Cash) MulByScalar(x *Cash, scalar int64) (*Cash, error) {
-	if !z.isCompatible(x) {
-		return nil, ErrIncompatible
+// split `Cash` as evenly as possible while nobody pays more than their cap
+// caps are in minor units; -1 means uncapped
+// whatever a capped participant can't take spills over to everyone else
+// caps only make sense for something owed, so a negative total is an error
+func (z *Cash) SplitWithCaps(caps []int64) ([]Cash, error) {
+	if err := z.RequireNonNegative(); err != nil {
+		return nil, err
 	}
-	z.Amt = x.Amt * scalar
-	return z, nil
-}
+	var (
+		l         = len(caps)
+		ret       = make([]Cash, l)
+		order     = make([]int, l)
+		remaining = z.Amt
+		left      = int64(l)
+	)
 
-// multiply `Cash` with a rational number
-// under the hood: math/big.Rat
-// has mathematical accuracy
-// good for consecutive mul (or div) operations
-func (z *Cash) MulByRat(x *Cash, p *big.Rat) (*Cash, error) {
-	if !z.isCompatible(x) {
-		return nil, ErrIncompatible
+	for i, c := range caps {
+		if c < -1 {
+			return nil, ErrBadCap
+		}
+		order[i] = i
+		ret[i] = *z
 	}
 
-	// turn integer cents to a rational number
-	var xR *big.Rat
-	if x.Rational == nil {
-		xR = big.NewRat(x.Amt, x.minorUnitFactor())
-	} else {
-		xR = x.Rational
+	// smallest caps first; uncapped participants last
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := caps[order[a]], caps[order[b]]
+		if ca == -1 {
+			return false
+		}
+		return cb == -1 || ca < cb
+	})
+
+	// saturate everyone whose cap is under the current fair share
+	k := 0
+	for ; k < l; k++ {
+		c := caps[order[k]]
+		if c == -1 || c > remaining/left {
+			break
+		}
+		ret[order[k]].SetCents(c)
+		remaining -= c
+		left--
 	}
 
-	// multiply fractions
-	z.Rational = new(big.Rat).Mul(xR, p)
+	if left == 0 {
+		if remaining != 0 {
+			return nil, ErrCapsExceeded
+		}
+		return ret, nil
+	}
 
-	// retrieve integer cents
-	// TODO this is slow as shit—restructure code to avoid this
-	s := z.Rational.FloatString(z.FracDigits)
-	_, err := z.SetString(s)
-	if err != nil {
-		return nil, err
+	// everyone else's cap is at least the fair share rounded up,
+	// so split the rest evenly; extra pennies go to the earliest participants
+	rest := order[k:]
+	sort.Ints(rest)
+	share, mod := remaining/left, remaining%left
+	for j, i := range rest {
+		ret[i].SetCents(share)
+		if int64(j) < mod {
+			ret[i].Amt += 1
+		}
 	}
 
-	return z, nil
+	return ret, nil
 }
 
-// multiplying two `Cash` money values
-// seems unlikely to be used at all
-// this is only here because it would look stupid if it weren't here
-func (z *Cash) MulByCash(x, y *Cash) (*Cash, error) {
-	if !z.isCompatible(x) || !z.isCompatible(y) {
-		return nil, ErrIncompatible
+// split `Cash` by `weights` with a cap per bucket, e.g. a refund across
+// gift cards with balance limits; a nil cap (or nil `caps`) is uncapped
+// what a full bucket can't take is re-split among the others by weight,
+// and whatever no bucket has room for comes back as the remainder
+func (z *Cash) Allocate(weights []int64, caps []*Cash) ([]Cash, *Cash, error) {
+	if err := z.RequireNonNegative(); err != nil {
+		return nil, nil, err
+	}
+	if caps != nil && len(caps) != len(weights) {
+		return nil, nil, fmt.Errorf("Allocate: %d weights but %d caps: %w", len(weights), len(caps), ErrBadCap)
+	}
+	if _, err := allocate(z.Amt, weights); err != nil {
+		return nil, nil, err
+	}
+	capOf := func(i int) *Cash {
+		if caps == nil {
+			return nil
+		}
+		return caps[i]
+	}
+	for i := range weights {
+		if c := capOf(i); c != nil {
+			if !z.isCompatible(c) {
+				return nil, nil, ErrIncompatible
+			}
+			if c.Amt < 0 {
+				return nil, nil, ErrBadCap
+			}
+		}
 	}
-	z.Amt = (x.Amt * y.Amt) / z.minorUnitFactor()
-	return z, nil
-}
 
-// divide `Cash` by a scalar integer N
-// return a slice of N respective `Cash` values
-// inspired by Martin Fowler's "allocate"
-func (z *Cash) DivByScalar(y int64) []Cash {
 	var (
-		i      int64
-		minima int64  = z.Amt / y
-		maxima int64  = minima + 1
-		mod    int64  = z.Amt % y
-		ret    []Cash = make([]Cash, y) // guarantee: y > mod
+		ret       = make([]Cash, len(weights))
+		remaining = z.Amt
+		active    []int // buckets with weight and room left
 	)
+	for i := range ret {
+		ret[i] = *fresh(z)
+		if weights[i] > 0 && (capOf(i) == nil || capOf(i).Amt > 0) {
+			active = append(active, i)
+		}
+	}
 
-	// first, assign maxima to res
-	// because sum(maxima - minima) over [0, mod) is less than 1
-	for i = 0; i < mod; i++ {
-		ret[i] = *z.SetCents(maxima) // keeping results consistent/compatible with input
+	// every round either places all of `remaining` or fills a bucket
+	for remaining > 0 && len(active) > 0 {
+		w := make([]int64, len(active))
+		for j, i := range active {
+			w[j] = weights[i]
+		}
+		parts, err := allocate(remaining, w)
+		if err != nil {
+			return nil, nil, err
+		}
+		next := active[:0]
+		for j, i := range active {
+			give := parts[j]
+			if c := capOf(i); c != nil && ret[i].Amt+give >= c.Amt {
+				give = c.Amt - ret[i].Amt
+				ret[i].Amt += give
+				remaining -= give
+				continue // full
+			}
+			ret[i].Amt += give
+			remaining -= give
+			next = append(next, i)
+		}
+		active = next
 	}
 
-	// then, assign minima to leftovers in res
-	for i = mod; i < y; i++ {
-		ret[i] = *z.SetCents(minima)
+	return ret, fresh(z).SetCents(remaining), nil
+}
+
+// split `Cash` into parts of exactly `step` minor units each,
+// plus one smaller part for whatever is left over
+// e.g., $23.00 in $5.00 steps -> $5, $5, $5, $5, $3
+func (z *Cash) SplitIntoSteps(step int64) ([]Cash, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("SplitIntoSteps(%d): %w", step, ErrNonPositiveDivisor)
+	}
+	var (
+		n   = z.Amt / step
+		rem = z.Amt % step
+	)
+	if n < 0 {
+		n, step = -n, -step // negative totals split into negative steps
+	}
+	ret := make([]Cash, 0, n+1)
+	for i := int64(0); i < n; i++ {
+		ret = append(ret, *z)
+		ret[i].SetCents(step)
 	}
+	if rem != 0 {
+		ret = append(ret, *z)
+		ret[n].SetCents(rem)
+	}
+	return ret, nil
+}
 
-	return ret
+// how many whole `unitMinor` lots fit in `Cash`, and what's left over
+// e.g., $10.37 in nickels is 207 nickels and 2 cents
+// for negative amounts both results carry the sign
+func (z *Cash) InUnitsOf(unitMinor int64) (whole int64, remainderMinor int64, err error) {
+	if unitMinor <= 0 {
+		return 0, 0, fmt.Errorf("InUnitsOf(%d): %w", unitMinor, ErrNonPositiveDivisor)
+	}
+	return z.Amt / unitMinor, z.Amt % unitMinor, nil
 }
 
-// divide `Cash` according to a set of numbers representing a ratio
-// return a slice of `Cash` values as long as the set (ratio)
-// inspired by Martin Fowler's "allocate"
-func (z *Cash) DivIntoRatio(ratio []int64) []Cash {
+// most bytes of table MakeChange will build for its search (a bool per
+// amount per denomination, plus a count per amount, after dividing out
+// the common factor) before giving up with ErrChangeTooLarge
+const maxChangeBytes = 16 << 20
+
+// work out change from a drawer with limited stock
+// `available` maps denomination (in minor units) to how many are on hand
+// returns how many of each to hand over, preferring larger denominations
+// plain greedy is tried first; only when it gets stuck is there a search,
+// which is bounded by maxChangeBytes
+func MakeChange(amount *Cash, available map[int64]int) (map[int64]int, error) {
+	if amount.Amt < 0 {
+		return nil, ErrNoChange
+	}
+	denoms := make([]int64, 0, len(available))
+	for d, n := range available {
+		if d <= 0 {
+			return nil, ErrBadDenomination
+		}
+		if n > 0 {
+			denoms = append(denoms, d)
+		}
+	}
+	sort.Slice(denoms, func(i, j int) bool { return denoms[i] > denoms[j] })
+
+	// greedy: as many of each as fit, largest first
+	// when that works it's also what the search below would pick
+	ret := make(map[int64]int)
+	rem := amount.Amt
+	for _, d := range denoms {
+		k := rem / d
+		if n := int64(available[d]); k > n {
+			k = n
+		}
+		if k > 0 {
+			ret[d] = int(k)
+			rem -= k * d
+		}
+	}
+	if rem == 0 {
+		return ret, nil
+	}
+
+	var (
+		l     = len(denoms)
+		total int64
+		g     int64 // every denomination is a multiple of g, so the amount must be
+	)
+	rem = amount.Amt
+	for _, d := range denoms {
+		g = gcd64(g, d)
+		if total < rem {
+			total += d * int64(available[d])
+		}
+	}
+	if total < rem || g == 0 || rem%g != 0 {
+		return nil, ErrNoChange
+	}
+	// work in units of g; the table shrinks by that factor
+	rem /= g
+	if rem >= maxChangeBytes/int64(l+1+4) {
+		return nil, ErrChangeTooLarge
+	}
+
+	// can[i][r]: denominations i onward can make exactly r (in units of g)
+	// bounded knapsack, one pass per denomination, O(amount × denominations)
 	var (
-		l           int    = len(ratio)
-		ret         []Cash = make([]Cash, l)
-		denominator int64
-		t           int64
+		can = make([][]bool, l+1)
+		// fewest of the current denomination on top of can[i+1] that reach r
+		// fits in 32 bits, as rem is below maxChangeBytes
+		used = make([]int32, rem+1)
 	)
+	can[l] = make([]bool, rem+1)
+	can[l][0] = true
+	for i := l - 1; i >= 0; i-- {
+		var (
+			d, n = denoms[i] / g, int64(available[denoms[i]])
+			next = can[i+1]
+			cur  = make([]bool, rem+1)
+		)
+		for r := int64(0); r <= rem; r++ {
+			switch {
+			case next[r]:
+				cur[r], used[r] = true, 0
+			case r >= d && cur[r-d] && int64(used[r-d]) < n:
+				cur[r], used[r] = true, used[r-d]+1
+			}
+		}
+		can[i] = cur
+	}
+	if !can[0][rem] {
+		return nil, ErrNoChange
+	}
+
+	ret = make(map[int64]int)
+	for i, d := range denoms {
+		// greedy first: as many of d as still leaves the rest makeable
+		k := rem / (d / g)
+		if n := int64(available[d]); k > n {
+			k = n
+		}
+		for !can[i+1][rem-k*(d/g)] {
+			k--
+		}
+		if k > 0 {
+			ret[d] = int(k)
+			rem -= k * (d / g)
+		}
+	}
+	return ret, nil
+}
 
-	for i := 0; i < l; i++ {
-		denominator += ratio[i] // summing parts of `ratio`
+func gcd64(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
 	}
+	return a
+}
+
+// one period of an amortization schedule
+type AmortizationRow struct {
+	Principal Cash // paid down this period
+	Interest  Cash // charged this period
+	Balance   Cash // left owing afterwards
+}
 
-	mod := z.Amt // start with whole; before subtracting
-	for j := 0; j < l; j++ {
-		t = z.Amt * ratio[j] / denominator
-		ret[j] = *z // shallow copy the context `Cash`
-		ret[j].SetCents(t)
-		mod -= t // ...eventually, actual modulus
+// standard fixed-payment amortization table
+// the payment is rounded once; the last period pays whatever balance is
+// left, so rounding drift never strands a cent and Balance ends at zero
+func Amortize(principal *Cash, ratePerPeriod *big.Rat, periods int) ([]AmortizationRow, error) {
+	if periods <= 0 {
+		return nil, ErrBadPeriods
+	}
+	if ratePerPeriod == nil || ratePerPeriod.Sign() < 0 {
+		return nil, ErrBadRate
 	}
 
-	// use up the modulus by adding 1, starting from i=0
-	var i int64 = 0
-	for ; i < mod; i++ {
-		ret[i].Amt += 1
+	// payment = P * r * (1+r)^n / ((1+r)^n - 1), or just P / n at 0%
+	var exact *big.Rat
+	if ratePerPeriod.Sign() == 0 {
+		exact = new(big.Rat).Quo(principal.Rat(), big.NewRat(int64(periods), 1))
+	} else {
+		growth := new(big.Rat).Add(big.NewRat(1, 1), ratePerPeriod)
+		compound := big.NewRat(1, 1)
+		for i := 0; i < periods; i++ {
+			compound.Mul(compound, growth)
+		}
+		exact = new(big.Rat).Mul(principal.Rat(), ratePerPeriod)
+		exact.Mul(exact, compound)
+		exact.Quo(exact, compound.Sub(compound, big.NewRat(1, 1)))
+	}
+	payment, err := ratToMinor(exact, principal.FracDigits, principal.Rounding)
+	if err != nil {
+		return nil, err
 	}
 
-	return ret
+	var (
+		ret     = make([]AmortizationRow, periods)
+		balance = principal.Amt
+		row     = *principal
+	)
+	row.Rational = nil
+	for i := range ret {
+		interest, err := ratToMinor(new(big.Rat).Mul(big.NewRat(balance, row.minorUnitFactor()), ratePerPeriod), row.FracDigits, row.Rounding)
+		if err != nil {
+			return nil, err
+		}
+		paid := payment - interest
+		if i == periods-1 {
+			paid = balance
+		}
+		balance -= paid
+
+		ret[i].Principal = row
+		ret[i].Principal.SetCents(paid)
+		ret[i].Interest = row
+		ret[i].Interest.SetCents(interest)
+		ret[i].Balance = row
+		ret[i].Balance.SetCents(balance)
+	}
+	return ret, nil
 }
 
 // database serialization
 func (z *Cash) Value() (driver.Value, error) {
-	return z.String(), nil
+	return z.wireString(), nil
 }
 
 // database deserialization
+// handles every type a driver.Value can hold; the ones that can't be
+// money (bool, time.Time, NULL) give ErrCannotScan instead of a panic
+// a code in a string column must match the receiver's, if it has one
 func (z *Cash) Scan(src interface{}) error {
 	switch src := src.(type) {
 	case int64:
-		// treat as cents
-		t := NewUSD().SetCents(src) // TODO come on, USD as default, really...?
+		// treat as minor units
+		t := z.scanTarget().SetCents(src)
 		*z = *t
 		return nil
 
-		// TODO float64
+	case float64:
+		// treat as major units; lossy, but that's the column's fault
+		t := z.scanTarget()
+		amt, err := floatToMinor(src, t.FracDigits, t.Rounding)
+		if err != nil {
+			return err
+		}
+		*z = *t.SetCents(amt)
+		return nil
+
+	case []byte:
+		return z.scanString(string(src))
+
+	case string:
+		return z.scanString(src)
 
 	default:
-		// treat as string
-		// works for MySQL
-		// check if quoted; if so, remove quotes
-		b := src.(string)
-		if len(b) > 2 && b[0] == '"' && b[len(b)-1] == '"' {
-			b = b[1 : len(b)-1]
-		}
-		t, err := NewUSD().SetString(b) // TODO generalize, not USD by default
-		*z = *t
+		// bool, time.Time, nil
+		return ErrCannotScan
+	}
+}
+
+// scans BIGINT columns holding value*10^Scale, where Scale isn't
+// the currency's own `FracDigits` (e.g. micro-dollars into USD)
+type ScaledScanner struct {
+	Cash
+	Scale int
+}
+
+var _ sql.Scanner = (*ScaledScanner)(nil)
+
+// scanner for a column stored at `scale`, read into `preset`'s format
+func NewScannerScaled(preset Cash, scale int) *ScaledScanner {
+	return &ScaledScanner{Cash: preset, Scale: scale}
+}
+
+// int64 is rescaled from `Scale` to `FracDigits` with rounding;
+// anything else is scanned like a plain `Cash`
+func (s *ScaledScanner) Scan(src interface{}) error {
+	if v, ok := src.(int64); ok {
+		_, err := s.SetScaledInt(v, s.Scale)
+		return err
+	}
+	return s.Cash.Scan(src)
+}
+
+// treat as string
+// works for MySQL
+func (z *Cash) scanString(b string) error {
+	// check if quoted; if so, remove quotes
+	if len(b) > 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	t, err := z.scanTarget().SetString(b)
+	if err != nil {
+		return err
+	}
+	// a EUR receiver doesn't quietly become USD
+	if err := t.keepsCode(z.Code); err != nil {
+		return err
+	}
+	*z = *t
+	return nil
+}
+
+// String() for storage and the wire: the display-only trimming of
+// Min/MaxFracDigits would round away digits that SetString can't get back,
+// and a ZeroPlaceholder like "—" isn't a number to anyone else
+func (z *Cash) wireString() string {
+	c := *z
+	c.MinFracDigits, c.MaxFracDigits = 0, 0
+	c.ZeroPlaceholder = ""
+	return c.String()
+}
+
+// fresh value carrying the receiver's format, so a EUR column or JSON
+// field decoded into a EUR receiver stays EUR
+// a zero-value receiver has no format to keep, and gets USD as before
+func (z *Cash) scanTarget() *Cash {
+	if z.Decimal == 0 {
+		return NewUSD()
+	}
+	t := *z
+	t.Amt = 0
+	t.Rational = nil
+	return &t
+}
+
+// wire shape for gob
+// a nil `Rational` is simply left out
+type gobCash struct {
+	Amt        int64
+	FracDigits int
+	Currency   rune
+	Decimal    rune
+	Thousands  rune
+	Code       string
+	Rational   *big.Rat
+}
+
+// gob.GobEncoder interface impl
+func (z *Cash) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(gobCash{
+		Amt:        z.Amt,
+		FracDigits: z.FracDigits,
+		Currency:   z.Currency,
+		Decimal:    z.Decimal,
+		Thousands:  z.Thousands,
+		Code:       z.Code,
+		Rational:   z.Rational,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gob.GobDecoder interface impl
+// fields outside the wire shape (rounding, display options) keep
+// whatever the receiver had
+func (z *Cash) GobDecode(b []byte) error {
+	var v gobCash
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
 		return err
 	}
+	z.Amt = v.Amt
+	z.FracDigits = v.FracDigits
+	z.Currency = v.Currency
+	z.Decimal = v.Decimal
+	z.Thousands = v.Thousands
+	z.Code = v.Code
+	z.Rational = v.Rational
+	return nil
+}
+
+// wire shape for JSONISOMinor
+// value is in minor units of the currency, never a decimal
+type isoMinorJSON struct {
+	Value    *int64 `json:"value"`
+	Currency string `json:"currency"`
+}
 
+// fail fast when an imported amount isn't in the expected currency
+// e.g., ingesting "USD 10.00" into a ledger that must be EUR
+func (z *Cash) MustBeCurrency(code string) error {
+	if z.Code == "" || !strings.EqualFold(z.Code, code) {
+		have := z.Code
+		if have == "" {
+			have = "no code"
+		}
+		return fmt.Errorf("%w: want %s, have %s", ErrWrongCurrency, code, have)
+	}
 	return nil
 }
 
 // json.Marshaler interface impl
 func (z *Cash) MarshalJSON() ([]byte, error) {
-	s := "\"" + z.String() + "\"" // add quotes
+	if z.JSONFormat == JSONISOMinor {
+		if z.Code == "" {
+			return nil, ErrNoCode
+		}
+		return json.Marshal(isoMinorJSON{Value: &z.Amt, Currency: z.Code})
+	}
+	if z.JSONFormat == JSONMinorUnits {
+		return []byte(strconv.FormatInt(z.Amt, 10)), nil
+	}
+	s := "\"" + z.wireString() + "\"" // add quotes
 	return []byte(s), nil
 }
 
 // json.Unmarshaler interface impl
+// accepts either the decimal string or the JSONISOMinor object;
+// a receiver in JSONMinorUnits mode reads a bare integer instead
 func (z *Cash) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '{' {
+		return z.unmarshalISOMinor(b)
+	}
+	if z.JSONFormat == JSONMinorUnits {
+		amt, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return err
+		}
+		z.Amt = amt
+		z.Rational = nil
+		return nil
+	}
 	// check if `b` is quoted; if so, unquote
 	if len(b) > 2 && b[0] == '"' && b[len(b)-1] == '"' {
 		b = b[1 : len(b)-1]
 	}
-	// output from `b`
-	t, err := NewUSD().SetString(string(b))
+	// output from `b`, in the receiver's format
+	t, err := z.scanTarget().SetString(string(b))
 	if err != nil {
 		return err
 	}
@@ -449,6 +2529,51 @@ func (z *Cash) UnmarshalJSON(b []byte) error {
 	return nil // fin
 }
 
+// encoding.TextMarshaler interface impl
+// same text as the JSON string form, without the quotes
+func (z *Cash) MarshalText() ([]byte, error) {
+	return []byte(z.wireString()), nil
+}
+
+// encoding.TextUnmarshaler interface impl
+// parses in the receiver's format, like UnmarshalJSON
+func (z *Cash) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		return ErrBadString
+	}
+	t, err := z.scanTarget().SetString(string(b))
+	if err != nil {
+		return err
+	}
+	*z = *t
+	return nil
+}
+
+// {"value": 1001897, "currency": "USD"} -> $10,018.97
+// takes the format of the registered preset for the code,
+// or keeps the receiver's own if its code matches
+func (z *Cash) unmarshalISOMinor(b []byte) error {
+	var v isoMinorJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Value == nil || v.Currency == "" {
+		return ErrBadString
+	}
+	if v.Currency != z.Code {
+		preset, ok := lookupCode(v.Currency)
+		if !ok {
+			return ErrUnknownCurrency
+		}
+		mode := z.JSONFormat
+		*z = preset
+		z.JSONFormat = mode
+	}
+	z.Amt = *v.Value
+	z.Rational = nil
+	return nil
+}
+
 // classic comparison
 func (z *Cash) Cmp(y *Cash) (int, error) {
 	if !z.isCompatible(y) {
@@ -467,6 +2592,67 @@ func (z *Cash) Cmp(y *Cash) (int, error) {
 	return -2, nil
 }
 
+// result of comparing two `Cash` values
+// the zero value is not a valid ordering; it only comes back with an error
+type Ordering int
+
+const (
+	Less Ordering = iota + 1
+	Equal
+	Greater
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case Less:
+		return "Less"
+	case Equal:
+		return "Equal"
+	case Greater:
+		return "Greater"
+	default:
+		return "Ordering(" + strconv.Itoa(int(o)) + ")"
+	}
+}
+
+// typed comparison
+// like Cmp() but without the magic numbers, so callers can switch exhaustively
+func (z *Cash) Order(y *Cash) (Ordering, error) {
+	if !z.isCompatible(y) {
+		return 0, ErrIncompatible
+	}
+
+	switch {
+	case z.Amt < y.Amt:
+		return Less, nil
+	case z.Amt > y.Amt:
+		return Greater, nil
+	default:
+		return Equal, nil
+	}
+}
+
+// reconcile against a float64 from some less careful system
+// f is converted to the receiver's precision, then compared within
+// `tolUnits` minor units either way
+func (z *Cash) EqualsFloat(f float64, tolUnits int64) (bool, error) {
+	amt, err := floatToMinor(f, z.FracDigits, z.Rounding)
+	if err != nil {
+		return false, err
+	}
+	if tolUnits < 0 {
+		return false, nil
+	}
+	// distance in uint64 so it can't overflow
+	var d uint64
+	if z.Amt >= amt {
+		d = uint64(z.Amt) - uint64(amt)
+	} else {
+		d = uint64(amt) - uint64(z.Amt)
+	}
+	return d <= uint64(tolUnits), nil
+}
+
 // is greater than
 func (z *Cash) IsGreaterThan(y *Cash) (bool, error) {
 	r, err := z.Cmp(y)
@@ -485,13 +2671,114 @@ func (z *Cash) IsLessThan(y *Cash) (bool, error) {
 	return r == -1, err
 }
 
+// comparable stand-in for use as a map key, e.g. "USD 12.99"
+// currency (the ISO code, else the symbol) plus the amount at its
+// precision, so $1.0 and $1.00 key differently; `Rational` and the
+// display fields are ignored
+func (z *Cash) Key() string {
+	cur := z.Code
+	if cur == "" && z.Currency != 0 {
+		cur = string(z.Currency)
+	}
+	if cur == "" {
+		return z.PlainString()
+	}
+	return cur + " " + z.PlainString()
+}
+
+// same currency and same economic value, whatever the display format
+// or precision: $1.0 at 1 digit equals $1.00 at 2, and "1.000,00" equals
+// "1,000.00"; never an error, a different currency is simply false
+func (z *Cash) EqualsValue(y *Cash) bool {
+	if !z.sameCurrency(y) {
+		return false
+	}
+	return z.Rat().Cmp(y.Rat()) == 0
+}
+
+// deep equality on every logical field; handy in test assertions
+// reflect.DeepEqual trips over the `Rational` pointer, this doesn't:
+// a nil `Rational` counts as the exact value of `Amt`
+func (z *Cash) SameAs(y *Cash) bool {
+	if z.Amt != y.Amt || z.FracDigits != y.FracDigits || z.Currency != y.Currency ||
+		z.Decimal != y.Decimal || z.Thousands != y.Thousands || z.Code != y.Code {
+		return false
+	}
+	if z.Rational == nil && y.Rational == nil {
+		return true
+	}
+	return z.exactRat().Cmp(y.exactRat()) == 0
+}
+
+// the retained rational if there is one, else the value of `Amt`
+func (z *Cash) exactRat() *big.Rat {
+	if z.Rational != nil {
+		return z.Rational
+	}
+	return z.Rat()
+}
+
+// strictly greater than zero; zero is not positive
 func (z *Cash) IsPositive() bool {
 	return z.Amt > 0
 }
 
+func (z *Cash) IsZero() bool {
+	return z.Amt == 0
+}
+
+func (z *Cash) IsNegative() bool {
+	return z.Amt < 0
+}
+
+// -1, 0, or +1, like big.Int.Sign
+func (z *Cash) Sign() int {
+	switch {
+	case z.Amt < 0:
+		return -1
+	case z.Amt > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// guard for balances that must never go negative, e.g. after a Sub()
+func (z *Cash) RequireNonNegative() error {
+	if z.Amt < 0 {
+		return fmt.Errorf("%w: %s", ErrNegativeAmount, z.String())
+	}
+	return nil
+}
+
 // errors
 var (
-	ErrBadString    = errors.New("malformed input string")
-	ErrIncompatible = errors.New("Cash values have incompatible fields")
-	ErrCannotScan   = errors.New("Scan() failed: Cannot convert passed value to data type")
+	ErrBadString       = errors.New("malformed input string")
+	ErrIncompatible    = errors.New("Cash values have incompatible fields")
+	ErrCannotScan      = errors.New("Scan() failed: Cannot convert passed value to data type")
+	ErrOverflow        = errors.New("amount does not fit in int64 minor units")
+	ErrBadScale        = errors.New("scale is out of range of the MinorUnit table")
+	ErrBadCap          = errors.New("cap must be -1 (uncapped) or non-negative")
+	ErrCapsExceeded    = errors.New("amount exceeds the sum of caps")
+	ErrEmpty           = errors.New("no values given")
+	ErrNoCode          = errors.New("Cash has no ISO 4217 currency code")
+	ErrUnknownCurrency = errors.New("unknown ISO 4217 currency code")
+	ErrWrongCurrency   = errors.New("unexpected currency")
+	ErrBadDays         = errors.New("days used must be within [0, days in period] and the period positive")
+	ErrNotFinite       = errors.New("float is NaN or infinite")
+	ErrBadDenomination = errors.New("denomination must be positive")
+	ErrNoChange        = errors.New("exact change can't be made from what's available")
+	ErrChangeTooLarge  = errors.New("amount too large to search for exact change")
+	ErrDivideByZero    = errors.New("division by zero")
+	ErrBadRatio        = errors.New("ratio must be non-negative with a positive total")
+	ErrBadCode         = errors.New("currency code must be three upper-case letters")
+	ErrNegativeAmount  = errors.New("amount is negative")
+	ErrBadPeriods      = errors.New("number of periods must be positive")
+	ErrBadRate         = errors.New("rate must not be negative")
+	ErrRoundTrip       = errors.New("String() and SetString() disagree")
+	ErrBadEnding       = errors.New("ending must be a fractional part in minor units")
+
+	// dividing or splitting into n parts (or n-sized parts) needs n > 0
+	// returned wrapped, with the method and n for context
+	ErrNonPositiveDivisor = errors.New("divisor must be positive")
 )
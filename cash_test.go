@@ -1,10 +1,20 @@
 package cash
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"math"
 	"math/big"
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestCreateFromString(t *testing.T) {
@@ -88,7 +98,8 @@ func TestMulByCash(t *testing.T) {
 func TestDivByScalar(t *testing.T) {
 	a := NewUSD().SetCents(100)
 	var scalar int64 = 3
-	res := a.DivByScalar(scalar)
+	res, err := a.DivByScalar(scalar)
+	assert.Nil(t, err)
 
 	assert.EqualValues(t, 34, res[0].Amt)
 	assert.EqualValues(t, 33, res[1].Amt)
@@ -100,7 +111,8 @@ func TestDivByScalar(t *testing.T) {
 func TestDivIntoRatio(t *testing.T) {
 	a := NewUSD().SetCents(100)
 	ratio := []int64{1, 1, 1}
-	res := a.DivIntoRatio(ratio)
+	res, err := a.DivIntoRatio(ratio)
+	assert.Nil(t, err)
 
 	/*
 		for i, v := range res {
@@ -165,4 +177,2485 @@ func TestRoundTrip(t *testing.T) {
 	actual, err = New(USD).SetString(expected.String())
 	assert.Nil(t, err)
 	assert.EqualValues(t, expected.Amt, actual.Amt)
-}
\ No newline at end of file
+}
+func TestOrder(t *testing.T) {
+	a := NewUSD().SetCents(2560)
+	b := NewUSD().SetCents(1840)
+
+	o, err := a.Order(b)
+	assert.Nil(t, err)
+	assert.EqualValues(t, Greater, o, "25.60 > 18.40")
+
+	o, err = b.Order(a)
+	assert.Nil(t, err)
+	assert.EqualValues(t, Less, o, "18.40 < 25.60")
+
+	o, err = a.Order(NewUSD().SetCents(2560))
+	assert.Nil(t, err)
+	assert.EqualValues(t, Equal, o, "25.60 == 25.60")
+
+	_, err = a.Order(New(EUR).SetCents(2560))
+	assert.Equal(t, ErrIncompatible, err, "USD and EUR can't be ordered")
+}
+
+func TestSameAs(t *testing.T) {
+	a := NewUSD().SetCents(1364)
+	a.Rational = big.NewRat(1363500, 100000)
+	b := NewUSD().SetCents(1364)
+	b.Rational = big.NewRat(13635, 1000) // different pointer, same value
+	assert.True(t, a.SameAs(b), "Rational differs only in pointer identity")
+
+	b.Rational = big.NewRat(1364, 100)
+	assert.False(t, a.SameAs(b), "Rational differs in value")
+
+	c := NewUSD().SetCents(1364)
+	d := NewUSD().SetCents(1364)
+	d.Rational = big.NewRat(1364, 100)
+	assert.True(t, c.SameAs(d), "nil Rational is the exact value of Amt")
+
+	assert.False(t, c.SameAs(New(EUR).SetCents(1364)), "different currency")
+}
+
+func TestSetScaledInt(t *testing.T) {
+	a, err := NewUSD().SetScaledInt(1234560, 6)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 123, a.Amt, "1.234560 at 2 digits is $1.23")
+	assert.EqualValues(t, "$1.23", a.String())
+
+	a, err = NewUSD().SetScaledInt(12350, 4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 124, a.Amt, "1.2350 rounds half-to-even to 1.24")
+
+	a, err = NewUSD().SetScaledInt(-12250, 4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -122, a.Amt, "-1.2250 rounds half-to-even to -1.22")
+
+	a, err = NewUSD().SetScaledInt(5, 0)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 500, a.Amt, "coarser feeds scale up")
+
+	_, err = NewUSD().SetScaledInt(math.MaxInt64/10, 0)
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = NewUSD().SetScaledInt(1, 42)
+	assert.Equal(t, ErrBadScale, err)
+}
+
+func TestParseInferred(t *testing.T) {
+	a, err := ParseInferred("10.5")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, a.FracDigits)
+	assert.EqualValues(t, 105, a.Amt)
+	assert.EqualValues(t, "10.5", a.String(), "no currency symbol")
+
+	b, err := ParseInferred("10.12345")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5, b.FracDigits)
+	assert.EqualValues(t, 1012345, b.Amt)
+
+	c, err := ParseInferred("0.12345678901234567890123")
+	assert.Nil(t, err)
+	assert.EqualValues(t, len(MinorUnit)-1, c.FracDigits, "capped at the table max")
+
+	_, err = ParseInferred("ten")
+	assert.NotNil(t, err)
+}
+
+func TestSplitWithCaps(t *testing.T) {
+	a := NewUSD().SetCents(10000)
+	res, err := a.SplitWithCaps([]int64{2000, -1, 2500})
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 3)
+	assert.EqualValues(t, 2000, res[0].Amt, "capped at $20.00")
+	assert.EqualValues(t, 5500, res[1].Amt, "uncapped takes the overflow")
+	assert.EqualValues(t, 2500, res[2].Amt, "capped at $25.00")
+
+	// caps above the fair share don't bind
+	res, err = a.SplitWithCaps([]int64{5000, -1, 5000})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3334, res[0].Amt)
+	assert.EqualValues(t, 3333, res[1].Amt)
+	assert.EqualValues(t, 3333, res[2].Amt)
+
+	_, err = a.SplitWithCaps([]int64{2000, 2000})
+	assert.Equal(t, ErrCapsExceeded, err)
+
+	_, err = a.SplitWithCaps([]int64{-5, -1})
+	assert.Equal(t, ErrBadCap, err)
+}
+
+func TestStringMinor(t *testing.T) {
+	a := New(BTC).SetCents(12345)
+	assert.EqualValues(t, "12,345 sats", a.StringMinor("sats"))
+
+	b := New(BTC).SetCents(500)
+	assert.EqualValues(t, "500 points", b.StringMinor("points"), "no leading separator")
+
+	c := New(BTC).SetCents(1234567)
+	assert.EqualValues(t, "1,234,567 sats", c.StringMinor("sats"))
+
+	d := New(BTC).SetCents(-12345)
+	assert.EqualValues(t, "(12,345 sats)", d.StringMinor("sats"))
+}
+
+func TestSumPromote(t *testing.T) {
+	a := NewUSD().SetCents(100)
+	b := NewUSD().SetCents(25)
+	b.FracDigits = 4
+	sum, err := SumPromote([]Cash{*a, *b})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, sum.FracDigits)
+	assert.EqualValues(t, 10025, sum.Amt, "$1.00 + $0.0025 == $1.0025")
+	assert.EqualValues(t, 100, a.Amt, "inputs untouched")
+
+	_, err = SumPromote([]Cash{*a, *New(EUR).SetCents(100)})
+	assert.Equal(t, ErrIncompatible, err)
+
+	cad := NewUSD().SetCents(100)
+	cad.Code = "CAD"
+	_, err = SumPromote([]Cash{*a, *cad})
+	assert.Equal(t, ErrIncompatible, err, "same $, different code")
+
+	bare := NewUSD().SetCents(100)
+	bare.Code = ""
+	_, err = SumPromote([]Cash{*bare, *a, *cad})
+	assert.Equal(t, ErrIncompatible, err)
+
+	_, err = SumPromote(nil)
+	assert.Equal(t, ErrEmpty, err)
+}
+
+func TestJSONISOMinor(t *testing.T) {
+	a := NewUSD().SetCents(1234)
+	a.JSONFormat = JSONISOMinor
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"value":1234,"currency":"USD"}`, string(b))
+
+	back := &Cash{JSONFormat: JSONISOMinor}
+	err = json.Unmarshal(b, back)
+	assert.Nil(t, err)
+	assert.True(t, back.SameAs(a), "USD round trip")
+	assert.EqualValues(t, JSONISOMinor, back.JSONFormat, "mode survives")
+
+	y := New(JPY).SetCents(1000)
+	y.JSONFormat = JSONISOMinor
+	b, err = json.Marshal(y)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"value":1000,"currency":"JPY"}`, string(b), "value is yen; no scaling")
+
+	back = new(Cash)
+	err = json.Unmarshal(b, back)
+	assert.Nil(t, err)
+	assert.True(t, back.SameAs(New(JPY).SetCents(1000)), "JPY round trip")
+
+	err = json.Unmarshal([]byte(`{"value":1,"currency":"XXX"}`), new(Cash))
+	assert.Equal(t, ErrUnknownCurrency, err)
+
+	_, err = json.Marshal(&Cash{JSONFormat: JSONISOMinor})
+	assert.NotNil(t, err, "no code to emit")
+}
+
+func TestMustBeCurrency(t *testing.T) {
+	a := New(EUR).SetCents(1000)
+	assert.Nil(t, a.MustBeCurrency("EUR"))
+	assert.Nil(t, a.MustBeCurrency("eur"), "codes are case-insensitive")
+
+	err := NewUSD().SetCents(1000).MustBeCurrency("EUR")
+	assert.True(t, errors.Is(err, ErrWrongCurrency))
+	assert.EqualValues(t, "unexpected currency: want EUR, have USD", err.Error())
+}
+
+func TestProrate(t *testing.T) {
+	a := NewUSD().SetCents(3000)
+	b, err := a.Prorate(10, 30)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1000, b.Amt, "$30.00 * 10/30 == $10.00")
+	assert.EqualValues(t, 3000, a.Amt, "source untouched")
+
+	b, err = a.Prorate(0, 30)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, b.Amt)
+
+	b, err = a.Prorate(30, 30)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3000, b.Amt)
+
+	b, err = NewUSD().SetCents(1000).Prorate(1, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 333, b.Amt, "$3.333... rounds to $3.33")
+
+	_, err = a.Prorate(1, 0)
+	assert.Equal(t, ErrBadDays, err)
+	_, err = a.Prorate(31, 30)
+	assert.Equal(t, ErrBadDays, err)
+	_, err = a.Prorate(-1, 30)
+	assert.Equal(t, ErrBadDays, err)
+}
+
+func TestSettle(t *testing.T) {
+	a := NewUSD().SetCents(1000)
+	b, err := NewUSD().SetExactMode(true).MulByRat(a, big.NewRat(1, 3))
+	assert.Nil(t, err)
+	b, err = b.MulByRat(b, big.NewRat(1, 3))
+	assert.Nil(t, err)
+	b, err = b.MulByRat(b, big.NewRat(9, 1))
+	assert.Nil(t, err)
+	assert.NotNil(t, b.Rational, "exact intermediate retained")
+
+	b.Settle()
+	assert.EqualValues(t, 1000, b.Amt, "$10.00 * 1/3 * 1/3 * 9 == $10.00 exactly")
+	assert.Nil(t, b.Rational)
+
+	c, err := NewUSD().SetExactMode(true).MulByRat(NewUSD().SetCents(1818), big.NewRat(3, 4))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1364, c.Settle().Amt, "13.635 rounds half-to-even")
+
+	d := NewUSD().SetCents(500)
+	assert.EqualValues(t, 500, d.Settle().Amt, "no Rational, no-op")
+}
+
+func TestEqualsFloat(t *testing.T) {
+	a := NewUSD().SetCents(1000)
+
+	eq, err := a.EqualsFloat(9.999999, 0)
+	assert.Nil(t, err)
+	assert.True(t, eq, "9.999999 is $10.00 at 2 digits")
+
+	eq, err = a.EqualsFloat(10.004, 0)
+	assert.Nil(t, err)
+	assert.True(t, eq, "10.004 is $10.00 at 2 digits")
+
+	eq, err = a.EqualsFloat(10.006, 0)
+	assert.Nil(t, err)
+	assert.False(t, eq, "10.006 is $10.01")
+
+	eq, err = a.EqualsFloat(10.006, 1)
+	assert.Nil(t, err)
+	assert.True(t, eq, "within one cent")
+
+	_, err = a.EqualsFloat(math.NaN(), 1)
+	assert.Equal(t, ErrNotFinite, err)
+}
+
+func TestSplitIntoSteps(t *testing.T) {
+	a := NewUSD().SetCents(2300)
+	res, err := a.SplitIntoSteps(500)
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 5)
+	for i := 0; i < 4; i++ {
+		assert.EqualValues(t, 500, res[i].Amt)
+	}
+	assert.EqualValues(t, 300, res[4].Amt, "remainder last")
+
+	res, err = NewUSD().SetCents(2000).SplitIntoSteps(500)
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 4, "no empty remainder part")
+
+	res, err = NewUSD().SetCents(-700).SplitIntoSteps(500)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -500, res[0].Amt)
+	assert.EqualValues(t, -200, res[1].Amt)
+
+	_, err = a.SplitIntoSteps(0)
+	assert.True(t, errors.Is(err, ErrNonPositiveDivisor))
+}
+
+func TestExactMode(t *testing.T) {
+	third := big.NewRat(1, 3)
+	three := big.NewRat(3, 1)
+
+	// default: round after every operation
+	a, err := NewUSD().MulByRat(NewUSD().SetCents(1000), third)
+	assert.Nil(t, err)
+	assert.Nil(t, a.Rational, "nothing retained")
+	a, err = a.MulByRat(a, three)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 999, a.Amt, "$3.33 * 3")
+
+	// exact: round once at the end
+	b, err := NewUSD().SetExactMode(true).MulByRat(NewUSD().SetCents(1000), third)
+	assert.Nil(t, err)
+	assert.NotNil(t, b.Rational, "exact value retained")
+	assert.EqualValues(t, 333, b.Amt, "Amt is still kept current")
+	b, err = b.MulByRat(b, three)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1000, b.Settle().Amt, "$10/3 * 3")
+}
+
+func TestMakeChange(t *testing.T) {
+	drawer := map[int64]int{25: 1, 10: 5, 5: 2, 1: 0}
+	change, err := MakeChange(NewUSD().SetCents(65), drawer)
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{25: 1, 10: 4}, change, "one quarter short; dimes make up the rest")
+
+	change, err = MakeChange(NewUSD().SetCents(30), map[int64]int{25: 1, 10: 0, 5: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{25: 1, 5: 1}, change)
+
+	// greedy would take the quarter and get stuck
+	change, err = MakeChange(NewUSD().SetCents(30), map[int64]int{25: 1, 10: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{10: 3}, change)
+
+	_, err = MakeChange(NewUSD().SetCents(30), map[int64]int{25: 2})
+	assert.Equal(t, ErrNoChange, err)
+
+	change, err = MakeChange(NewUSD(), map[int64]int{25: 2})
+	assert.Nil(t, err)
+	assert.Empty(t, change)
+}
+
+func TestMakeChangeImpossible(t *testing.T) {
+	// plenty of stock, but only even denominations for an odd amount;
+	// a backtracking search never finishes this
+	drawer := map[int64]int{10: 100, 8: 100, 6: 100, 4: 100, 2: 100}
+	start := time.Now()
+	_, err := MakeChange(NewUSD().SetCents(1001), drawer)
+	assert.Equal(t, ErrNoChange, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	change, err := MakeChange(NewUSD().SetCents(1000), drawer)
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{10: 100}, change)
+
+	_, err = MakeChange(NewUSD().SetCents(3), map[int64]int{2: 5})
+	assert.Equal(t, ErrNoChange, err)
+}
+
+func TestMakeChangeLarge(t *testing.T) {
+	drawer := map[int64]int{10000: 5000, 2000: 5000, 500: 5000, 100: 5000, 25: 5000, 10: 5000, 5: 5000, 1: 5000}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	change, err := MakeChange(NewUSD().SetCents(10000000), drawer)
+	runtime.ReadMemStats(&after)
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{10000: 1000}, change, "$100,000 in hundreds")
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20, "greedy, no table")
+
+	// greedy gets stuck (a quarter leaves 5), and the search would be huge
+	_, err = MakeChange(NewUSD().SetCents(100000030), map[int64]int{25: 1 << 30, 10: 3})
+	assert.Equal(t, ErrChangeTooLarge, err)
+
+	// the common factor keeps the search small: 40,000,300 is 800,006 fifties
+	change, err = MakeChange(NewUSD().SetCents(40000300), map[int64]int{250: 1 << 30, 100: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]int{250: 160000, 100: 3}, change)
+}
+
+func TestImpliedRate(t *testing.T) {
+	r, err := ImpliedRate(NewUSD().SetCents(10000), NewUSD().SetCents(14000))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "2/5", r.String(), "$100.00 -> $140.00 is +40%")
+
+	r, err = ImpliedRate(NewUSD().SetCents(10000), NewUSD().SetCents(7500))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "-1/4", r.String(), "$100.00 -> $75.00 is -25%")
+
+	_, err = ImpliedRate(NewUSD(), NewUSD().SetCents(100))
+	assert.Equal(t, ErrDivideByZero, err)
+
+	_, err = ImpliedRate(NewUSD().SetCents(100), New(EUR).SetCents(100))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestSetStringCodeAndParens(t *testing.T) {
+	a, err := NewUSD().SetString("USD (1,234.56)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, a.Amt)
+	assert.EqualValues(t, "USD", a.Code)
+
+	a, err = NewUSD().SetString("(USD 1,234.56)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, a.Amt, "code inside the parentheses")
+
+	// a different registered code takes that currency's format
+	a, err = New(EUR).SetString("USD (1,234.56)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, a.Amt)
+	assert.EqualValues(t, '$', a.Currency)
+	assert.EqualValues(t, "USD", a.Code)
+
+	eu := New(EUR)
+	eu.Decimal, eu.Thousands = ',', '.'
+	b, err := eu.SetString("(€1.234,56)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, b.Amt)
+	assert.EqualValues(t, "EUR", b.Code)
+
+	_, err = NewUSD().SetString("XYZ 1.00")
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+func TestInUnitsOf(t *testing.T) {
+	whole, rem, err := NewUSD().SetCents(1037).InUnitsOf(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 207, whole, "207 nickels")
+	assert.EqualValues(t, 2, rem, "2 cents left over")
+
+	whole, rem, err = NewUSD().SetCents(-1037).InUnitsOf(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -207, whole)
+	assert.EqualValues(t, -2, rem)
+
+	_, _, err = NewUSD().SetCents(1037).InUnitsOf(0)
+	assert.True(t, errors.Is(err, ErrNonPositiveDivisor))
+}
+
+func TestScanDriverTypes(t *testing.T) {
+	cases := []struct {
+		src interface{}
+		amt int64
+		err error
+	}{
+		{int64(6629), 6629, nil},
+		{float64(55.1), 5510, nil},
+		{[]byte("55.10"), 5510, nil},
+		{"55.10", 5510, nil},
+		{true, 0, ErrCannotScan},
+		{time.Now(), 0, ErrCannotScan},
+		{nil, 0, ErrCannotScan},
+		{math.Inf(1), 0, ErrNotFinite},
+		{"not money", 0, strconv.ErrSyntax},
+	}
+	for _, c := range cases {
+		q := new(Cash)
+		var err error
+		assert.NotPanics(t, func() { err = q.Scan(c.src) }, "%T", c.src)
+		if c.err != nil {
+			assert.True(t, errors.Is(err, c.err), "%T: got %v", c.src, err)
+			continue
+		}
+		assert.Nil(t, err, "%T", c.src)
+		assert.EqualValues(t, c.amt, q.Amt, "%T", c.src)
+	}
+}
+
+func TestSetFromFraction(t *testing.T) {
+	a, err := NewUSD().SetFromFraction(5, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 167, a.Amt, "5/3 dollars is $1.67")
+	assert.EqualValues(t, "-1/300", a.Residual(big.NewRat(5, 3)).String(), "rounded up by 1/300 of a dollar")
+
+	a, err = NewUSD().SetFromFraction(-5, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -167, a.Amt)
+
+	_, err = NewUSD().SetFromFraction(5, 0)
+	assert.Equal(t, ErrDivideByZero, err)
+}
+
+func TestMinMaxFracDigits(t *testing.T) {
+	a := NewUSD()
+	a.FracDigits = 4
+	a.MinFracDigits, a.MaxFracDigits = 2, 4
+
+	assert.EqualValues(t, "$1.50", a.SetCents(15000).String(), "trailing zeros trimmed to min")
+	assert.EqualValues(t, "$1.5075", a.SetCents(15075).String(), "all 4 digits kept")
+	assert.EqualValues(t, "$1.507", a.SetCents(15070).String(), "trimmed between the bounds")
+
+	a.MaxFracDigits = 3
+	assert.EqualValues(t, "$1.508", a.SetCents(15075).String(), "rounded to max")
+
+	b := NewUSD().SetCents(150)
+	assert.EqualValues(t, "$1.50", b.String(), "unset: plain FracDigits")
+}
+
+func TestNeedsRounding(t *testing.T) {
+	assert.False(t, NewUSD().SetCents(100).NeedsRounding(0), "$1.00 -> $1")
+	assert.True(t, NewUSD().SetCents(105).NeedsRounding(0), "$1.05 -> $1")
+	assert.True(t, NewUSD().SetCents(-105).NeedsRounding(1), "-$1.05 -> -$1.1")
+	assert.False(t, NewUSD().SetCents(105).NeedsRounding(4), "finer never rounds")
+}
+
+func TestAllocateTo(t *testing.T) {
+	target := NewUSD().SetCents(10001)
+	res, err := AllocateTo(target, []int64{1, 1, 1})
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 3)
+	assert.EqualValues(t, 3334, res[0].Amt)
+	assert.EqualValues(t, 3334, res[1].Amt)
+	assert.EqualValues(t, 3333, res[2].Amt)
+	assert.EqualValues(t, 10001, res[0].Amt+res[1].Amt+res[2].Amt, "target conserved")
+
+	res, err = AllocateTo(NewUSD().SetCents(-10001), []int64{1, 1, 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, -3334, res[0].Amt)
+	assert.EqualValues(t, -3333, res[2].Amt)
+
+	res, err = AllocateTo(NewUSD().SetCents(math.MaxInt64), []int64{1, 3})
+	assert.Nil(t, err)
+	assert.EqualValues(t, int64(math.MaxInt64), res[0].Amt+res[1].Amt, "no intermediate overflow")
+
+	_, err = AllocateTo(target, nil)
+	assert.Equal(t, ErrBadRatio, err)
+	_, err = AllocateTo(target, []int64{0, 0})
+	assert.Equal(t, ErrBadRatio, err)
+	_, err = AllocateTo(target, []int64{2, -1})
+	assert.Equal(t, ErrBadRatio, err)
+}
+
+func TestCurrencyInfo(t *testing.T) {
+	sym, code, fd := NewUSD().CurrencyInfo()
+	assert.EqualValues(t, "$", sym)
+	assert.EqualValues(t, "USD", code)
+	assert.EqualValues(t, 2, fd)
+
+	dinar := Cash{Currency: 'د', FracDigits: 3, Decimal: '.', Thousands: ',', Code: "KWD"}
+	assert.Nil(t, Register(dinar))
+	sym, code, fd = New(dinar).CurrencyInfo()
+	assert.EqualValues(t, "د", sym)
+	assert.EqualValues(t, "KWD", code)
+	assert.EqualValues(t, 3, fd)
+
+	sym, code, fd = (&Cash{Currency: '¤', FracDigits: 1}).CurrencyInfo()
+	assert.EqualValues(t, "¤", sym)
+	assert.EqualValues(t, "", code, "unregistered")
+	assert.EqualValues(t, 1, fd)
+
+	assert.Equal(t, ErrNoCode, Register(Cash{Currency: '¤'}))
+	assert.Equal(t, ErrBadCode, Register(Cash{Code: "dollars"}))
+}
+
+func TestMulByDecimalString(t *testing.T) {
+	price := NewUSD().SetCents(1999)
+	tax, err := NewUSD().MulByDecimalString(price, "0.0825")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 165, tax.Amt, "19.99 * 0.0825 == 1.649175")
+
+	_, err = NewUSD().MulByDecimalString(price, "abc")
+	assert.Equal(t, ErrBadString, err)
+}
+
+func TestMinOfMaxOf(t *testing.T) {
+	values := []Cash{
+		*NewUSD().SetCents(1200),
+		*NewUSD().SetCents(-350),
+		*NewUSD().SetCents(9900),
+		*NewUSD().SetCents(0),
+	}
+	min, err := MinOf(values)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -350, min.Amt)
+
+	max, err := MaxOf(values)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 9900, max.Amt)
+
+	_, err = MinOf(nil)
+	assert.Equal(t, ErrEmpty, err)
+	_, err = MaxOf([]Cash{})
+	assert.Equal(t, ErrEmpty, err)
+
+	_, err = MaxOf(append(values, *New(EUR).SetCents(1)))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestAbsDiff(t *testing.T) {
+	a := NewUSD().SetCents(1000)
+	b := NewUSD().SetCents(300)
+	d, err := NewUSD().AbsDiff(a, b)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 700, d.Amt)
+
+	d, err = NewUSD().AbsDiff(b, a)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 700, d.Amt, "order doesn't matter")
+
+	_, err = NewUSD().AbsDiff(NewUSD().SetCents(math.MaxInt64), NewUSD().SetCents(-1))
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = NewUSD().AbsDiff(a, New(EUR).SetCents(300))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestZeroPlaceholder(t *testing.T) {
+	a := NewUSD()
+	assert.EqualValues(t, "$0.00", a.String())
+
+	a.ZeroPlaceholder = "—"
+	assert.EqualValues(t, "—", a.String())
+	assert.EqualValues(t, "$0.01", a.SetCents(1).String(), "only for zero")
+}
+
+func TestAllocateNamed(t *testing.T) {
+	res, err := NewUSD().SetCents(10000).AllocateNamed(map[string]int64{"c": 1, "b": 1, "a": 1})
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 3)
+	assert.EqualValues(t, 3334, res["a"].Amt, "first sorted key gets the extra cent")
+	assert.EqualValues(t, 3333, res["b"].Amt)
+	assert.EqualValues(t, 3333, res["c"].Amt)
+
+	_, err = NewUSD().SetCents(10000).AllocateNamed(map[string]int64{})
+	assert.Equal(t, ErrBadRatio, err)
+}
+
+func TestSetStringMinus(t *testing.T) {
+	eu := New(EUR)
+	eu.Decimal, eu.Thousands = ',', '.'
+	a, err := eu.SetString("1.234,56-")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, a.Amt, "trailing minus, EUR separators")
+
+	b, err := NewUSD().SetString("10.00-")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1000, b.Amt, "trailing minus, USD separators")
+
+	c, err := NewUSD().SetString("-1.05")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -105, c.Amt, "leading minus applies to the whole amount")
+
+	d, err := NewUSD().SetString("-$1,234.56")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, d.Amt, "minus before the symbol")
+
+	d, err = NewUSD().SetString("$-1,234.56")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456, d.Amt, "minus after the symbol")
+
+	_, err = NewUSD().SetString("(-1.05)")
+	assert.Equal(t, ErrBadString, err)
+	_, err = NewUSD().SetString("--1.05")
+	assert.Equal(t, ErrBadString, err)
+}
+
+func TestRequireNonNegative(t *testing.T) {
+	assert.Nil(t, NewUSD().SetCents(100).RequireNonNegative())
+	assert.Nil(t, NewUSD().RequireNonNegative(), "zero is fine")
+
+	err := NewUSD().SetCents(-100).RequireNonNegative()
+	assert.True(t, errors.Is(err, ErrNegativeAmount))
+	assert.EqualValues(t, "amount is negative: ($1.00)", err.Error())
+}
+
+func TestAmortize(t *testing.T) {
+	loan := NewUSD().SetCents(100000)
+	rows, err := Amortize(loan, big.NewRat(1, 100), 3)
+	assert.Nil(t, err)
+	assert.True(t, len(rows) == 3)
+
+	// payment of $340.02, 1% per period
+	assert.EqualValues(t, 1000, rows[0].Interest.Amt)
+	assert.EqualValues(t, 33002, rows[0].Principal.Amt)
+	assert.EqualValues(t, 66998, rows[0].Balance.Amt)
+	assert.EqualValues(t, 670, rows[1].Interest.Amt)
+	assert.EqualValues(t, 33332, rows[1].Principal.Amt)
+	assert.EqualValues(t, 337, rows[2].Interest.Amt)
+	assert.EqualValues(t, 33666, rows[2].Principal.Amt, "last payment absorbs rounding")
+	assert.EqualValues(t, 0, rows[2].Balance.Amt, "ends at exactly $0.00")
+
+	var paid int64
+	for _, r := range rows {
+		paid += r.Principal.Amt
+	}
+	assert.EqualValues(t, loan.Amt, paid, "all principal repaid")
+
+	rows, err = Amortize(loan, new(big.Rat), 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, rows[2].Balance.Amt, "0% works too")
+
+	_, err = Amortize(loan, big.NewRat(1, 100), 0)
+	assert.Equal(t, ErrBadPeriods, err)
+	_, err = Amortize(loan, big.NewRat(-1, 100), 3)
+	assert.Equal(t, ErrBadRate, err)
+	_, err = Amortize(loan, nil, 3)
+	assert.Equal(t, ErrBadRate, err)
+}
+
+func TestParseFormValue(t *testing.T) {
+	for _, v := range []string{"$1,234.56", "1234.56", " 1,234.56 "} {
+		a, err := ParseFormValue(USD, v)
+		assert.Nil(t, err, v)
+		assert.EqualValues(t, 123456, a.Amt, v)
+		assert.EqualValues(t, "USD", a.Code, v)
+	}
+
+	a, err := ParseFormValue(USD, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, a.Amt, "empty means zero")
+
+	a, err = ParseFormValue(USD, "   ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, a.Amt, "blank means zero")
+
+	_, err = ParseFormValue(USD, "12abc")
+	assert.NotNil(t, err)
+
+	a, err = ParseFormValue(USD, "USD 5.00")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 500, a.Amt)
+
+	_, err = ParseFormValue(USD, "JPY 100000")
+	assert.True(t, errors.Is(err, ErrWrongCurrency))
+}
+
+func TestScanKeepsCode(t *testing.T) {
+	e := New(EUR).SetCents(1)
+	err := e.Scan("USD 5.00")
+	assert.True(t, errors.Is(err, ErrWrongCurrency))
+	assert.EqualValues(t, "EUR", e.Code)
+	assert.EqualValues(t, 1, e.Amt)
+
+	assert.Nil(t, e.Scan("EUR 5.10"))
+	assert.EqualValues(t, 510, e.Amt)
+
+	// nothing to keep: a zero value takes whatever the column says
+	var z Cash
+	assert.Nil(t, z.Scan("EUR 5.10"))
+	assert.EqualValues(t, "EUR", z.Code)
+}
+
+func TestStringWithCode(t *testing.T) {
+	assert.EqualValues(t, "1234.56 USD", NewUSD().SetCents(123456).StringWithCode())
+	assert.EqualValues(t, "-0.05 USD", NewUSD().SetCents(-5).StringWithCode())
+	assert.EqualValues(t, "1000 JPY", New(JPY).SetCents(1000).StringWithCode())
+	assert.EqualValues(t, "0.00012345 BTC", New(BTC).SetCents(12345).StringWithCode())
+}
+
+func TestHalfUnit(t *testing.T) {
+	assert.EqualValues(t, "1/200", HalfUnit(USD).String(), "0.005")
+	assert.EqualValues(t, "1/2", HalfUnit(JPY).String())
+
+	// $1.00 * 0.005 == $0.005, a tie between $0.00 and $0.01
+	a, err := NewUSD().MulByRat(NewUSD().SetCents(100), HalfUnit(USD))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, a.Amt, "half-to-even rounds down to 0")
+
+	// $3.00 * 0.005 == $0.015, a tie between $0.01 and $0.02
+	a, err = NewUSD().MulByRat(NewUSD().SetCents(300), HalfUnit(USD))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, a.Amt, "half-to-even rounds up to 2")
+}
+
+func TestClampToBand(t *testing.T) {
+	ref := NewUSD().SetCents(10000)
+	tenPct := big.NewRat(1, 10)
+
+	a, err := NewUSD().SetCents(15000).ClampToBand(ref, tenPct)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 11000, a.Amt, "$150.00 clamps to $110.00")
+
+	a, err = NewUSD().SetCents(5000).ClampToBand(ref, tenPct)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 9000, a.Amt, "$50.00 clamps to $90.00")
+
+	a, err = NewUSD().SetCents(10500).ClampToBand(ref, tenPct)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10500, a.Amt, "inside the band, untouched")
+
+	a, err = NewUSD().SetCents(0).ClampToBand(NewUSD().SetCents(999), big.NewRat(1, 3))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 666, a.Amt, "lower bound 6.66 rounds inward")
+
+	_, err = NewUSD().SetCents(15000).ClampToBand(New(EUR).SetCents(10000), tenPct)
+	assert.Equal(t, ErrIncompatible, err)
+	_, err = NewUSD().SetCents(15000).ClampToBand(ref, nil)
+	assert.Equal(t, ErrBadRate, err)
+	_, err = NewUSD().SetCents(15000).ClampToBand(ref, big.NewRat(-1, 10))
+	assert.Equal(t, ErrBadRate, err)
+}
+
+func TestNonPositiveDivisor(t *testing.T) {
+	a := NewUSD().SetCents(2300)
+	for _, n := range []int64{0, -1} {
+		assert.NotPanics(t, func() {
+			_, err := a.DivByScalar(n)
+			assert.True(t, errors.Is(err, ErrNonPositiveDivisor), "DivByScalar(%d)", n)
+
+			_, err = a.SplitIntoSteps(n)
+			assert.True(t, errors.Is(err, ErrNonPositiveDivisor), "SplitIntoSteps(%d)", n)
+
+			_, _, err = a.InUnitsOf(n)
+			assert.True(t, errors.Is(err, ErrNonPositiveDivisor), "InUnitsOf(%d)", n)
+		})
+	}
+
+	_, err := a.DivByScalar(-1)
+	assert.EqualValues(t, "DivByScalar(-1): divisor must be positive", err.Error())
+}
+
+func TestStringDelta(t *testing.T) {
+	assert.EqualValues(t, "+$1.50", NewUSD().SetCents(150).StringDelta())
+	assert.EqualValues(t, "-$0.75", NewUSD().SetCents(-75).StringDelta())
+	assert.EqualValues(t, "$0.00", NewUSD().StringDelta())
+	assert.EqualValues(t, "-$1,234.56", NewUSD().SetCents(-123456).StringDelta())
+	assert.EqualValues(t, "-$92,233,720,368,547,758.08", NewUSD().SetCents(math.MinInt64).StringDelta())
+
+	eur := New(EUR).SetCents(-150)
+	eur.Negative = NegParens
+	assert.EqualValues(t, "-€1.50", eur.StringDelta(), "never parentheses")
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	samples := []int64{0, 1, 5, 99, 100, 12345, 123456789, -1, -123456}
+	assert.Nil(t, NewUSD().VerifyRoundTrip(samples), "USD")
+	assert.Nil(t, New(JPY).VerifyRoundTrip(samples), "JPY")
+	assert.Nil(t, New(BTC).VerifyRoundTrip(samples), "BTC")
+
+	// SetString reads the receiver's own placeholder back as zero
+	dashes := NewUSD()
+	dashes.ZeroPlaceholder = "—"
+	assert.Nil(t, dashes.VerifyRoundTrip(samples), "placeholder")
+
+	// display trimming that rounds can't come back
+	coarse := New(BTC)
+	coarse.MaxFracDigits = 2
+	err := coarse.VerifyRoundTrip(samples)
+	assert.True(t, errors.Is(err, ErrRoundTrip))
+	assert.Contains(t, err.Error(), `1 formats as "฿0"`)
+}
+
+func TestScannerScaled(t *testing.T) {
+	s := NewScannerScaled(USD, 6)
+	assert.Nil(t, s.Scan(int64(1234560)))
+	assert.EqualValues(t, 123, s.Amt, "1.234560 at scale 6 is $1.23")
+	assert.EqualValues(t, "$1.23", s.String())
+
+	assert.Nil(t, s.Scan(int64(1235000)))
+	assert.EqualValues(t, 124, s.Amt, "ties round half-to-even")
+
+	assert.Equal(t, ErrCannotScan, s.Scan(nil))
+}
+
+func TestApplyChanges(t *testing.T) {
+	start := NewUSD().SetCents(10000)
+	pcts := []*big.Rat{big.NewRat(10, 100), big.NewRat(-5, 100), big.NewRat(20, 100)}
+	end, err := NewUSD().ApplyChanges(start, pcts)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 12540, end.Amt, "100 * 1.10 * 0.95 * 1.20 == 125.40")
+	assert.EqualValues(t, 10000, start.Amt, "start untouched")
+
+	end, err = NewUSD().ApplyChanges(NewUSD().SetCents(3333), []*big.Rat{big.NewRat(1, 3), big.NewRat(1, 3)})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5925, end.Amt, "33.33 * 16/9 == 59.2533...")
+
+	_, err = NewUSD().ApplyChanges(New(EUR).SetCents(100), pcts)
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestJSONMinorUnits(t *testing.T) {
+	for _, preset := range []Cash{USD, BTC} {
+		a := New(preset).SetCents(-123456789012)
+		a.JSONFormat = JSONMinorUnits
+		b, err := json.Marshal(a)
+		assert.Nil(t, err)
+		assert.EqualValues(t, "-123456789012", string(b), preset.Code)
+
+		back := New(preset)
+		back.JSONFormat = JSONMinorUnits
+		assert.Nil(t, json.Unmarshal(b, back), preset.Code)
+		assert.EqualValues(t, a.Amt, back.Amt, preset.Code)
+		assert.True(t, back.SameAs(a), preset.Code)
+	}
+
+	back := NewUSD()
+	back.JSONFormat = JSONMinorUnits
+	assert.NotNil(t, json.Unmarshal([]byte(`"12.34"`), back), "decimal string isn't minor units")
+}
+
+func TestDedupe(t *testing.T) {
+	values := []Cash{
+		*NewUSD().SetCents(1000),
+		*NewUSD().SetCents(500),
+		*NewUSD().SetCents(1000),
+		*NewUSD().SetCents(2000),
+		*NewUSD().SetCents(1000),
+	}
+	res, err := Dedupe(values)
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 3)
+	assert.EqualValues(t, 1000, res[0].Amt, "first-seen order")
+	assert.EqualValues(t, 500, res[1].Amt)
+	assert.EqualValues(t, 2000, res[2].Amt)
+
+	_, err = Dedupe(append(values, *New(EUR).SetCents(1000)))
+	assert.Equal(t, ErrIncompatible, err)
+
+	res, err = Dedupe(nil)
+	assert.Nil(t, err)
+	assert.True(t, len(res) == 0)
+}
+
+func TestMakeStringLargeNegative(t *testing.T) {
+	a := NewUSD().SetCents(-123456789)
+	assert.EqualValues(t, "($1,234,567.89)", a.String(), "grouping applies inside the parentheses")
+	assert.EqualValues(t, -123456789, a.Amt, "sign restored")
+
+	b := NewUSD().SetCents(-100000000000)
+	assert.EqualValues(t, "($1,000,000,000.00)", b.String())
+
+	c := NewUSD().SetCents(-12345)
+	assert.EqualValues(t, "($123.45)", c.String(), "no leading separator")
+}
+
+func TestSnapToEndings(t *testing.T) {
+	a, err := NewUSD().SetCents(1037).SnapToEndings([]int64{95, 99})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 999, a.Amt, "$10.37 -> $9.99, across the whole-unit wrap")
+
+	a, err = NewUSD().SetCents(1080).SnapToEndings([]int64{95, 99})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1095, a.Amt, "$10.80 -> $10.95")
+
+	a, err = NewUSD().SetCents(1097).SnapToEndings([]int64{95, 99})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1099, a.Amt, "tie between .95 and .99 goes up")
+
+	_, err = NewUSD().SetCents(1037).SnapToEndings(nil)
+	assert.Equal(t, ErrEmpty, err)
+	_, err = NewUSD().SetCents(1037).SnapToEndings([]int64{100})
+	assert.Equal(t, ErrBadEnding, err)
+}
+
+type fictionalProvider map[string]int
+
+func (p fictionalProvider) Lookup(code string) (string, int, bool) {
+	fd, ok := p[code]
+	if code == "CHF" {
+		return "CHF", 2, true
+	}
+	return "¤", fd, ok
+}
+
+func TestCurrencyProvider(t *testing.T) {
+	SetCurrencyProvider(fictionalProvider{"ZZZ": 3})
+	defer SetCurrencyProvider(nil)
+
+	a, err := NewFromCode("ZZZ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, '¤', a.Currency)
+	assert.EqualValues(t, 3, a.FracDigits)
+	assert.EqualValues(t, "ZZZ", a.Code)
+
+	_, err = a.SetString("1.234")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "¤1.234", a.String())
+
+	b, err := NewUSD().SetString("ZZZ 2.500")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2500, b.Amt, "ISO parser goes through the provider")
+	assert.EqualValues(t, "ZZZ", b.Code)
+
+	_, err = NewFromCode("USD")
+	assert.Equal(t, ErrUnknownCurrency, err, "custom provider replaces the registry")
+
+	_, err = NewFromCode("CHF")
+	assert.Equal(t, ErrUnknownCurrency, err, "a symbol must be one rune")
+	_, err = NewUSD().SetString("CHF 5.00")
+	assert.Equal(t, ErrUnknownCurrency, err)
+
+	SetCurrencyProvider(nil)
+	c, err := NewFromCode("USD")
+	assert.Nil(t, err)
+	assert.True(t, c.SameAs(NewUSD()), "built-in registry restored")
+}
+
+func TestImmutableOps(t *testing.T) {
+	x := NewUSD().SetCents(1000)
+	y := NewUSD().SetCents(-250)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s, err := Add(x, y)
+				assert.Nil(t, err)
+				assert.EqualValues(t, 750, s.Amt)
+
+				d, err := Sub(x, y)
+				assert.Nil(t, err)
+				assert.EqualValues(t, 1250, d.Amt)
+
+				n, err := Neg(y)
+				assert.Nil(t, err)
+				assert.EqualValues(t, 250, n.Amt)
+
+				m, err := MulByScalar(x, 3)
+				assert.Nil(t, err)
+				assert.EqualValues(t, 3000, m.Amt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1000, x.Amt, "inputs untouched")
+	assert.EqualValues(t, -250, y.Amt, "inputs untouched")
+
+	_, err := Neg(NewUSD().SetCents(math.MinInt64))
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSetStringNegative(t *testing.T) {
+	a, err := New(USD).SetString("-1.05")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -105, a.Amt, "sign applies to the combined amount, not just -1")
+
+	a, err = New(USD).SetString("-0.01")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1, a.Amt, "negative with a zero integer part")
+
+	a, err = New(USD).SetString("-12.99")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1299, a.Amt)
+
+	a, err = New(USD).SetString("($12.99)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1299, a.Amt, "parenthesized form from String()")
+
+	b := New(USD).SetCents(-1)
+	a, err = New(USD).SetString(b.String())
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1, a.Amt, "round trip through ($0.01)")
+}
+
+func TestStringConcurrent(t *testing.T) {
+	a := NewUSD().SetCents(-1001897)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				assert.EqualValues(t, "($10,018.97)", a.String())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, -1001897, a.Amt, "amount unchanged")
+
+	b := NewUSD().SetCents(math.MinInt64)
+	assert.EqualValues(t, "($92,233,720,368,547,758.08)", b.String(), "most-negative int64 doesn't wrap")
+}
+
+func TestNegMethod(t *testing.T) {
+	a := New(EUR).SetCents(1234)
+	b := New(USD).Neg(a)
+	assert.EqualValues(t, -1234, b.Amt)
+	assert.EqualValues(t, '€', b.Currency, "format comes from x")
+	assert.EqualValues(t, 1234, a.Amt, "x untouched")
+
+	assert.EqualValues(t, 0, New(USD).Neg(New(USD)).Amt, "-0 == 0")
+
+	c := New(USD).Neg(New(USD).Neg(a))
+	assert.True(t, c.SameAs(a), "double negation")
+
+	a.Neg(a)
+	assert.EqualValues(t, -1234, a.Amt, "in place")
+}
+
+func TestAbs(t *testing.T) {
+	a := NewUSD().SetCents(-1234)
+	b, err := NewUSD().Abs(a)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1234, b.Amt)
+	assert.EqualValues(t, -1234, a.Amt, "x untouched")
+
+	b, err = NewUSD().Abs(NewUSD().SetCents(1234))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1234, b.Amt)
+
+	_, err = NewUSD().Abs(NewUSD().SetCents(math.MinInt64))
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSignPredicates(t *testing.T) {
+	zero := NewUSD()
+	assert.True(t, zero.IsZero())
+	assert.False(t, zero.IsPositive(), "zero isn't positive")
+	assert.False(t, zero.IsNegative(), "zero isn't negative")
+	assert.EqualValues(t, 0, zero.Sign())
+
+	cent := NewUSD().SetCents(1)
+	assert.False(t, cent.IsZero())
+	assert.True(t, cent.IsPositive())
+	assert.False(t, cent.IsNegative())
+	assert.EqualValues(t, 1, cent.Sign())
+
+	negCent := NewUSD().SetCents(-1)
+	assert.False(t, negCent.IsZero())
+	assert.False(t, negCent.IsPositive())
+	assert.True(t, negCent.IsNegative())
+	assert.EqualValues(t, -1, negCent.Sign())
+}
+
+func TestRoundingModes(t *testing.T) {
+	cases := []struct {
+		mode     RoundingMode
+		pos, neg int64
+	}{
+		{RoundHalfEven, 66700, -66700},
+		{RoundHalfUp, 66700, -66700},
+		{RoundHalfDown, 66699, -66699},
+		{RoundCeil, 66700, -66699},
+		{RoundFloor, 66699, -66700},
+		{RoundTowardZero, 66699, -66699},
+	}
+	for _, c := range cases {
+		a, err := NewUSD().SetRoundingMode(c.mode).SetString("666.995")
+		assert.Nil(t, err)
+		assert.EqualValues(t, c.pos, a.Amt, "666.995 mode %d", c.mode)
+
+		a, err = NewUSD().SetRoundingMode(c.mode).SetString("-666.995")
+		assert.Nil(t, err)
+		assert.EqualValues(t, c.neg, a.Amt, "-666.995 mode %d", c.mode)
+	}
+
+	// tie to an even neighbor: half-even and half-up disagree
+	a, err := NewUSD().SetString("666.985")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 66698, a.Amt, "default stays banker's rounding")
+	a, err = NewUSD().SetRoundingMode(RoundHalfUp).SetString("666.985")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 66699, a.Amt)
+
+	// digits past the first extra one still count
+	a, err = NewUSD().SetRoundingMode(RoundHalfDown).SetString("666.9951")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 66700, a.Amt, "above the tie")
+
+	// the mode also applies to division-like operations
+	b, err := NewUSD().SetRoundingMode(RoundFloor).SetFromFraction(5, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 166, b.Amt)
+}
+
+func TestRoundHalfEvenNegative(t *testing.T) {
+	a, err := NewUSD().SetString("-1.005")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -100, a.Amt, "-1.005 -> -1.00")
+
+	a, err = NewUSD().SetString("1.005")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 100, a.Amt, "mirror image of -1.005")
+
+	y, err := New(JPY).SetString("-2.5")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -2, y.Amt, "-2.5 -> -2")
+
+	y, err = New(JPY).SetString("-3.5")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -4, y.Amt, "-3.5 -> -4")
+
+	// the integer helper, both sides of zero
+	for _, c := range [][2]int64{{25, 2}, {-25, -2}, {35, 4}, {-35, -4}, {-26, -3}, {-24, -2}} {
+		assert.EqualValues(t, c[1], roundQuo(c[0], 10, RoundHalfEven), "%d/10", c[0])
+	}
+}
+
+func TestNewFromFloat64(t *testing.T) {
+	a, err := NewUSD().NewFromFloat64(0.1 + 0.2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 30, a.Amt)
+	assert.Equal(t, "$0.30", a.String())
+
+	a, err = NewUSD().NewFromFloat64(-19.99)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1999, a.Amt)
+
+	a, err = NewUSD().SetRoundingMode(RoundCeil).NewFromFloat64(1.001)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 101, a.Amt)
+
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err = NewUSD().NewFromFloat64(f)
+		assert.True(t, errors.Is(err, ErrNotFinite))
+	}
+
+	_, err = NewUSD().NewFromFloat64(1e300)
+	assert.True(t, errors.Is(err, ErrOverflow))
+}
+
+func TestFloat64(t *testing.T) {
+	a, _ := NewUSD().SetString("$10,018.97")
+	assert.Equal(t, 10018.97, a.Float64())
+	assert.Equal(t, "10018.97", a.Float64String(2))
+	assert.Equal(t, "10018.9700", a.Float64String(4))
+	assert.Equal(t, "10019.0", a.Float64String(1))
+
+	a.Amt = -5
+	assert.Equal(t, -0.05, a.Float64())
+	assert.Equal(t, "-0.05", a.Float64String(2))
+
+	y := New(JPY).SetCents(1234)
+	assert.Equal(t, 1234.0, y.Float64())
+	assert.Equal(t, "1234", y.Float64String(0))
+}
+
+func TestSetStringSymbolAndThousands(t *testing.T) {
+	cases := []struct {
+		in  string
+		amt int64
+	}{
+		{"$10,018.97", 1001897},
+		{"$1,234,567.89", 123456789},
+		{"($1,234,567.89)", -123456789},
+		{"($0.05)", -5},
+	}
+	for _, c := range cases {
+		a, err := NewUSD().SetString(c.in)
+		assert.Nil(t, err, c.in)
+		assert.EqualValues(t, c.amt, a.Amt, c.in)
+		assert.Equal(t, c.in, a.String(), "round trip %s", c.in)
+	}
+
+	e, err := New(EUR).SetString(New(EUR).SetCents(-123456789).String())
+	assert.Nil(t, err)
+	assert.EqualValues(t, -123456789, e.Amt)
+}
+
+func TestScanKeepsReceiverFormat(t *testing.T) {
+	e := New(EUR)
+	assert.Nil(t, e.Scan("5.10"))
+	assert.EqualValues(t, 510, e.Amt)
+	assert.Equal(t, '€', e.Currency)
+	assert.Equal(t, "EUR", e.Code)
+
+	e = New(EUR)
+	assert.Nil(t, e.Scan(int64(510)))
+	assert.EqualValues(t, 510, e.Amt)
+	assert.Equal(t, '€', e.Currency)
+
+	y := New(JPY)
+	assert.Nil(t, y.Scan(float64(1234)))
+	assert.EqualValues(t, 1234, y.Amt)
+	assert.Equal(t, "¥1,234", y.String())
+
+	// nothing to keep: still USD
+	var z Cash
+	assert.Nil(t, z.Scan("$5.10"))
+	assert.EqualValues(t, 510, z.Amt)
+	assert.Equal(t, '$', z.Currency)
+}
+
+func TestUnmarshalJSONKeepsReceiverFormat(t *testing.T) {
+	type invoice struct {
+		Total Cash `json:"total"`
+	}
+	in := invoice{Total: *New(EUR).SetCents(1001897)}
+	b, err := json.Marshal(&in)
+	assert.Nil(t, err)
+
+	out := invoice{Total: *New(EUR)}
+	assert.Nil(t, json.Unmarshal(b, &out))
+	assert.EqualValues(t, 1001897, out.Total.Amt)
+	assert.Equal(t, '€', out.Total.Currency)
+	assert.Equal(t, in.Total.String(), out.Total.String())
+}
+
+func TestJSONFormatPerInstance(t *testing.T) {
+	type quote struct {
+		Display Cash `json:"display"`
+		Raw     Cash `json:"raw"`
+	}
+	in := quote{
+		Display: *NewUSD().SetCents(1001897),
+		Raw:     *NewUSD().SetJSONFormat(JSONMinorUnits).SetCents(1001897),
+	}
+	b, err := json.Marshal(&in)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"display":"$10,018.97","raw":1001897}`, string(b))
+
+	out := quote{Display: *NewUSD(), Raw: *NewUSD().SetJSONFormat(JSONMinorUnits)}
+	assert.Nil(t, json.Unmarshal(b, &out))
+	assert.EqualValues(t, 1001897, out.Display.Amt)
+	assert.EqualValues(t, 1001897, out.Raw.Amt)
+}
+
+func TestText(t *testing.T) {
+	a := New(EUR).SetCents(-123456789)
+	b, err := a.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, a.String(), string(b))
+
+	back := New(EUR)
+	assert.Nil(t, back.UnmarshalText(b))
+	assert.EqualValues(t, a.Amt, back.Amt)
+	assert.Equal(t, '€', back.Currency)
+
+	assert.Equal(t, ErrBadString, New(EUR).UnmarshalText(nil))
+	assert.Equal(t, ErrBadString, New(EUR).UnmarshalText([]byte{}))
+}
+
+func TestClone(t *testing.T) {
+	a := NewUSD().SetCents(1001897)
+	a.Rational = big.NewRat(1001897, 100)
+
+	c := a.Clone()
+	assert.True(t, c.SameAs(a))
+	c.Rational.SetInt64(7)
+	c.Amt = 1
+	assert.EqualValues(t, 1001897, a.Amt)
+	assert.Equal(t, 0, a.Rational.Cmp(big.NewRat(1001897, 100)), "original untouched")
+
+	b := NewUSD().SetCents(5).Clone()
+	assert.Nil(t, b.Rational)
+	assert.EqualValues(t, 5, b.Amt)
+}
+
+func TestDivMod(t *testing.T) {
+	x := NewUSD().SetCents(1000)
+	q, r, err := NewUSD().DivMod(x, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 333, q.Amt)
+	assert.EqualValues(t, 1, r)
+	assert.EqualValues(t, 1000, x.Amt, "x untouched")
+
+	q, r, err = NewUSD().DivMod(NewUSD().SetCents(-100), 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -34, q.Amt, "floored")
+	assert.EqualValues(t, 2, r)
+
+	q, r, err = NewUSD().DivMod(NewUSD().SetCents(-99), 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -33, q.Amt)
+	assert.EqualValues(t, 0, r)
+
+	q, r, err = NewUSD().DivMod(NewUSD().SetCents(-1), 4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1, q.Amt)
+	assert.EqualValues(t, 3, r)
+
+	_, _, err = NewUSD().DivMod(x, 0)
+	assert.True(t, errors.Is(err, ErrDivideByZero))
+	assert.True(t, errors.Is(err, ErrNonPositiveDivisor))
+	_, _, err = NewUSD().DivMod(x, -2)
+	assert.True(t, errors.Is(err, ErrNonPositiveDivisor))
+	_, _, err = New(EUR).DivMod(x, 2)
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestDivByScalarLeavesReceiver(t *testing.T) {
+	z := NewUSD().SetCents(1000)
+	shares, err := z.DivByScalar(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1000, z.Amt, "z is not a scratch variable")
+	assert.EqualValues(t, []int64{334, 333, 333}, []int64{shares[0].Amt, shares[1].Amt, shares[2].Amt})
+
+	shares[0].Amt = 0
+	assert.EqualValues(t, 1000, z.Amt, "shares don't alias z")
+}
+
+func TestDivideNegativeTotal(t *testing.T) {
+	sum := func(cs []Cash) (n int64) {
+		for _, c := range cs {
+			n += c.Amt
+		}
+		return
+	}
+
+	shares, err := NewUSD().SetCents(-100).DivByScalar(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{-34, -33, -33}, []int64{shares[0].Amt, shares[1].Amt, shares[2].Amt})
+	assert.EqualValues(t, -100, sum(shares))
+
+	parts, err := NewUSD().SetCents(-1000).DivIntoRatio([]int64{1, 1, 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{-334, -333, -333}, []int64{parts[0].Amt, parts[1].Amt, parts[2].Amt})
+	assert.EqualValues(t, -1000, sum(parts))
+
+	parts, err = NewUSD().SetCents(-1001).DivIntoRatio([]int64{3, 7})
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1001, sum(parts))
+	assert.EqualValues(t, -301, parts[0].Amt)
+}
+
+func TestDivByScalarZero(t *testing.T) {
+	a := NewUSD().SetCents(1000)
+	assert.NotPanics(t, func() {
+		shares, err := a.DivByScalar(0)
+		assert.True(t, errors.Is(err, ErrDivideByZero))
+		assert.True(t, errors.Is(err, ErrNonPositiveDivisor), "still n <= 0")
+		assert.Nil(t, shares)
+	})
+
+	_, err := a.DivByScalar(-1)
+	assert.True(t, errors.Is(err, ErrNonPositiveDivisor))
+}
+
+func TestPercent(t *testing.T) {
+	price := NewUSD().SetCents(1999)
+
+	tip, err := NewUSD().Percent(price, big.NewRat(15, 1))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 300, tip.Amt, "2.9985 -> 3.00")
+
+	tax, err := NewUSD().Percent(price, big.NewRat(8875, 1000))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 177, tax.Amt, "1.7741125 -> 1.77")
+	assert.EqualValues(t, 1999, price.Amt)
+
+	// 6% of $0.75 is exactly 4.5 cents
+	x := NewUSD().SetCents(75)
+	tax, _ = NewUSD().Percent(x, big.NewRat(6, 1))
+	assert.EqualValues(t, 4, tax.Amt, "half-even")
+	tax, _ = NewUSD().SetRoundingMode(RoundHalfUp).Percent(x, big.NewRat(6, 1))
+	assert.EqualValues(t, 5, tax.Amt, "half-up")
+
+	_, err = New(EUR).Percent(price, big.NewRat(15, 1))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestMulByBasisPoints(t *testing.T) {
+	fee, err := NewUSD().MulByBasisPoints(NewUSD().SetCents(1000000), 25)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2500, fee.Amt, "25 bps of $10,000.00")
+
+	// 50 bps of $1.00 and $3.00: 0.5 and 1.5 cents, ties to even
+	fee, _ = NewUSD().MulByBasisPoints(NewUSD().SetCents(100), 50)
+	assert.EqualValues(t, 0, fee.Amt)
+	fee, _ = NewUSD().MulByBasisPoints(NewUSD().SetCents(300), 50)
+	assert.EqualValues(t, 2, fee.Amt)
+	fee, _ = NewUSD().MulByBasisPoints(NewUSD().SetCents(-300), 50)
+	assert.EqualValues(t, -2, fee.Amt, "-1.5 -> -2")
+	fee, _ = NewUSD().SetRoundingMode(RoundHalfUp).MulByBasisPoints(NewUSD().SetCents(100), 50)
+	assert.EqualValues(t, 1, fee.Amt)
+
+	// same answer as the big.Rat path
+	for _, amt := range []int64{1, 99, 12345, -98765, 1001897} {
+		for _, bps := range []int64{1, 25, 333, 10000, -75} {
+			x := NewUSD().SetCents(amt)
+			a, _ := NewUSD().MulByBasisPoints(x, bps)
+			b, _ := NewUSD().MulByRat(x, big.NewRat(bps, 10000))
+			assert.EqualValues(t, b.Amt, a.Amt, "%d @ %d bps", amt, bps)
+		}
+	}
+
+	// x*bps overflows int64 but the result doesn't
+	fee, err = NewUSD().MulByBasisPoints(NewUSD().SetCents(math.MaxInt64/2), 100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, (math.MaxInt64/2)/100, fee.Amt)
+
+	_, err = New(EUR).MulByBasisPoints(NewUSD(), 25)
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestMinMax(t *testing.T) {
+	floor := NewUSD().SetCents(100)
+	ceiling := NewUSD().SetCents(2500)
+	fee := NewUSD().SetCents(3130)
+
+	clamped, err := Min(fee, ceiling)
+	assert.Nil(t, err)
+	assert.True(t, clamped == ceiling, "points at an argument")
+	clamped, err = Max(floor, clamped)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2500, clamped.Amt)
+
+	lo, _ := Min(fee, NewUSD().SetCents(-5), floor)
+	assert.EqualValues(t, -5, lo.Amt)
+	hi, _ := Max(fee)
+	assert.True(t, hi == fee)
+
+	_, err = Min()
+	assert.Equal(t, ErrEmpty, err)
+	_, err = Max()
+	assert.Equal(t, ErrEmpty, err)
+
+	_, err = Max(fee, New(EUR).SetCents(1))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestSum(t *testing.T) {
+	total, err := Sum(NewUSD().SetCents(1999), NewUSD().SetCents(-500), NewUSD().SetCents(1))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1500, total.Amt)
+	assert.Equal(t, '$', total.Currency)
+
+	_, err = Sum()
+	assert.Equal(t, ErrEmpty, err)
+	_, err = Sum(NewUSD(), New(EUR))
+	assert.Equal(t, ErrIncompatible, err)
+	_, err = Sum(NewUSD().SetCents(math.MaxInt64), NewUSD().SetCents(1))
+	assert.Equal(t, ErrOverflow, err)
+
+	xs := make([]*Cash, 100000)
+	for i := range xs {
+		xs[i] = NewUSD().SetCents(int64(i))
+	}
+	total, err = Sum(xs...)
+	assert.Nil(t, err)
+	assert.EqualValues(t, int64(len(xs))*int64(len(xs)-1)/2, total.Amt)
+	allocs := testing.AllocsPerRun(10, func() { Sum(xs...) })
+	assert.True(t, allocs <= 1, "only the result is allocated, got %v", allocs)
+}
+
+func TestAddSubOverflow(t *testing.T) {
+	max := New(BTC).SetCents(math.MaxInt64)
+	min := New(BTC).SetCents(math.MinInt64)
+	one := New(BTC).SetCents(1)
+
+	_, err := New(BTC).Add(max, one)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(BTC).Add(min, New(BTC).SetCents(-1))
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(BTC).Sub(min, one)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(BTC).Sub(max, New(BTC).SetCents(-1))
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(BTC).Sub(New(BTC), min)
+	assert.Equal(t, ErrOverflow, err, "0 - MinInt64")
+	_, err = Add(max, max)
+	assert.Equal(t, ErrOverflow, err)
+
+	// right at the edge is fine
+	z, err := New(BTC).Add(max, min)
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1, z.Amt)
+	z, err = New(BTC).Sub(max, max)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, z.Amt)
+	z, err = New(BTC).Sub(New(BTC).SetCents(-1), max)
+	assert.Nil(t, err)
+	assert.EqualValues(t, int64(math.MinInt64), z.Amt)
+}
+
+func TestMulOverflow(t *testing.T) {
+	huge := New(BTC).SetCents(math.MaxInt64 / 3)
+
+	_, err := New(BTC).MulByScalar(huge, 4)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(BTC).MulByScalar(huge, -4)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = MulByScalar(New(BTC).SetCents(math.MinInt64), -1)
+	assert.Equal(t, ErrOverflow, err)
+	z, err := New(BTC).MulByScalar(huge, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, (math.MaxInt64/3)*3, z.Amt)
+
+	// 10 BTC * 10 BTC is fine even though the raw product isn't an int64
+	ten := New(BTC).SetCents(10 * 100000000)
+	z, err = New(BTC).MulByCash(ten, ten)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 100*100000000, z.Amt)
+
+	_, err = New(BTC).MulByCash(huge, ten)
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSetPrec(t *testing.T) {
+	a := NewUSD().SetCents(1299)
+	_, err := a.SetPrec(0)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 13, a.Amt)
+	assert.Equal(t, "$13", a.String())
+
+	a = NewUSD().SetCents(1299)
+	_, err = a.SetPrec(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 129900, a.Amt)
+	assert.Equal(t, "$12.9900", a.String())
+
+	// ties follow the rounding mode
+	a = NewUSD().SetCents(1250)
+	a.SetPrec(0)
+	assert.EqualValues(t, 12, a.Amt)
+	a = NewUSD().SetRoundingMode(RoundHalfUp).SetCents(1250)
+	a.SetPrec(0)
+	assert.EqualValues(t, 13, a.Amt)
+	a = NewUSD().SetCents(-1299)
+	a.SetPrec(1)
+	assert.EqualValues(t, -130, a.Amt)
+
+	_, err = NewUSD().SetCents(math.MaxInt64 / 10).SetPrec(4)
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSetPrecOutOfRange(t *testing.T) {
+	for _, prec := range []int{-1, 19, 100} {
+		a := NewUSD().SetCents(1299)
+		assert.NotPanics(t, func() {
+			_, err := a.SetPrec(prec)
+			assert.True(t, errors.Is(err, ErrBadScale), "SetPrec(%d)", prec)
+			assert.Equal(t, "$12.99", a.String(), "unchanged")
+		})
+	}
+	_, err := NewUSD().SetPrec(18)
+	assert.Nil(t, err)
+}
+
+func TestEighteenDigits(t *testing.T) {
+	wei := Cash{FracDigits: 18, Currency: 'Ξ', Decimal: '.', Thousands: ','}
+
+	a := New(wei).SetCents(1234567890123456789)
+	assert.Equal(t, "Ξ1.234567890123456789", a.String())
+	back, err := New(wei).SetString(a.String())
+	assert.Nil(t, err)
+	assert.EqualValues(t, a.Amt, back.Amt)
+
+	a = New(wei).SetCents(-1)
+	assert.Equal(t, "(Ξ0.000000000000000001)", a.String())
+	back, err = New(wei).SetString(a.String())
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1, back.Amt)
+
+	// past the table is still an error, not a panic
+	_, err = New(wei).SetPrec(19)
+	assert.True(t, errors.Is(err, ErrBadScale))
+}
+
+func TestConvert(t *testing.T) {
+	usd := NewUSD().SetCents(10000)
+
+	e, err := NewUSD().Convert(usd, EUR, big.NewRat(92, 100))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 9200, e.Amt)
+	assert.Equal(t, "EUR", e.Code)
+	assert.Equal(t, '€', e.Currency)
+	assert.EqualValues(t, 10000, usd.Amt, "x untouched")
+
+	// 100/60000 BTC = 0.0016666...
+	b, err := new(Cash).Convert(usd, BTC, big.NewRat(1, 60000))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 8, b.FracDigits)
+	assert.EqualValues(t, 166667, b.Amt)
+
+	y, err := new(Cash).Convert(NewUSD().SetCents(-1999), JPY, big.NewRat(1495, 10))
+	assert.Nil(t, err)
+	assert.EqualValues(t, -2989, y.Amt, "-2988.505 -> -2989")
+
+	_, err = new(Cash).Convert(usd, EUR, big.NewRat(-1, 1))
+	assert.Equal(t, ErrBadRate, err)
+	_, err = new(Cash).Convert(usd, EUR, nil)
+	assert.Equal(t, ErrBadRate, err)
+}
+
+func TestCompatibleByCode(t *testing.T) {
+	cad := USD
+	cad.Code = "CAD"
+	_, err := NewUSD().Add(NewUSD().SetCents(100), New(cad).SetCents(100))
+	assert.Equal(t, ErrIncompatible, err, "both $, different codes")
+
+	// no code on one side: the symbol decides
+	bare := USD
+	bare.Code = ""
+	z, err := NewUSD().Add(NewUSD().SetCents(100), New(bare).SetCents(100))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 200, z.Amt)
+
+	// same code wins over a restyled symbol
+	usd2 := USD
+	usd2.Currency = 'U'
+	_, err = NewUSD().Add(NewUSD(), New(usd2))
+	assert.Nil(t, err)
+
+	gbp, err := NewFromCode("GBP")
+	assert.Nil(t, err)
+	assert.Equal(t, "£1,234.50", gbp.SetCents(123450).String())
+	_, err = New(GBP).Add(New(GBP), New(EUR))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestJPY(t *testing.T) {
+	cases := []struct {
+		amt int64
+		s   string
+	}{
+		{0, "¥0"},
+		{5, "¥5"},
+		{1000, "¥1,000"},
+		{1234567, "¥1,234,567"},
+		{-1234567, "(¥1,234,567)"},
+	}
+	for _, c := range cases {
+		y := New(JPY).SetCents(c.amt)
+		assert.Equal(t, c.s, y.String())
+
+		back, err := New(JPY).SetString(c.s)
+		assert.Nil(t, err, c.s)
+		assert.EqualValues(t, c.amt, back.Amt, c.s)
+	}
+}
+
+func TestSymbolPlacement(t *testing.T) {
+	eu := EUR
+	eu.Decimal, eu.Thousands = ',', '.'
+
+	cases := []struct {
+		after, space bool
+		pos, neg     string
+	}{
+		{false, false, "€1.234,56", "(€1.234,56)"},
+		{false, true, "€ 1.234,56", "(€ 1.234,56)"},
+		{true, false, "1.234,56€", "(1.234,56€)"},
+		{true, true, "1.234,56 €", "(1.234,56 €)"},
+	}
+	for _, c := range cases {
+		preset := eu
+		preset.SymbolAfter, preset.SymbolSpace = c.after, c.space
+
+		a := New(preset).SetCents(123456)
+		assert.Equal(t, c.pos, a.String())
+		a.Amt = -123456
+		assert.Equal(t, c.neg, a.String())
+
+		back, err := New(preset).SetString(c.pos)
+		assert.Nil(t, err, c.pos)
+		assert.EqualValues(t, 123456, back.Amt, c.pos)
+		back, err = New(preset).SetString(c.neg)
+		assert.Nil(t, err, c.neg)
+		assert.EqualValues(t, -123456, back.Amt, c.neg)
+	}
+
+	a := New(eu)
+	a.SymbolAfter, a.SymbolSpace = true, true
+	assert.Equal(t, "10,00 €", a.SetCents(1000).String())
+	assert.Equal(t, "-10,00 €", a.SetCents(-1000).StringDelta())
+}
+
+func TestSetLocale(t *testing.T) {
+	want := map[string]string{
+		"en-US": "$1,234,567.89",
+		"de-DE": "1.234.567,89 $",
+		"fr-FR": "1\u00a0234\u00a0567,89 $",
+		"ja-JP": "$1,234,568",
+	}
+	for tag, s := range want {
+		a := NewUSD().SetCents(123456789)
+		_, err := a.SetLocale(Locales[tag])
+		assert.Nil(t, err, tag)
+		assert.Equal(t, s, a.String(), tag)
+
+		back, err := New(*a).SetString(s)
+		assert.Nil(t, err, tag)
+		assert.EqualValues(t, a.Amt, back.Amt, tag)
+	}
+
+	e, _ := New(EUR).SetCents(1000).SetLocale(Locales["de-DE"])
+	assert.Equal(t, "10,00 €", e.String())
+
+	_, err := NewUSD().SetLocale(Locale{FracDigits: 42})
+	assert.True(t, errors.Is(err, ErrBadScale))
+}
+
+func TestNegativeStyle(t *testing.T) {
+	cases := []struct {
+		style NegativeStyle
+		want  string
+	}{
+		{NegParens, "($1,234.56)"},
+		{NegMinus, "-$1,234.56"},
+		{NegMinusAfter, "$1,234.56-"},
+	}
+	for _, c := range cases {
+		a := NewUSD().SetCents(-123456)
+		a.Negative = c.style
+		assert.Equal(t, c.want, a.String())
+		assert.Equal(t, "$1,234.56", a.SetCents(123456).String(), "positives unaffected")
+
+		back, err := NewUSD().SetString(c.want)
+		assert.Nil(t, err, c.want)
+		assert.EqualValues(t, -123456, back.Amt, c.want)
+	}
+
+	e := New(EUR).SetCents(-1000)
+	e.Negative, e.SymbolAfter, e.SymbolSpace = NegMinus, true, true
+	assert.Equal(t, "-10.00 €", e.String())
+	assert.Equal(t, "-10.00 €", e.StringDelta())
+	back, err := New(*e).SetString(e.String())
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1000, back.Amt)
+}
+
+func TestCommafy(t *testing.T) {
+	cases := map[string]string{
+		"":          "",
+		"0":         "0",
+		"1":         "1",
+		"12":        "12",
+		"123":       "123",
+		"1234":      "1,234",
+		"12345":     "12,345",
+		"123456":    "123,456",
+		"1000000":   "1,000,000",
+		"100000000": "100,000,000",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, commafy(in, ','), in)
+	}
+	assert.Equal(t, "1\u00a0000\u00a0000", commafy("1000000", '\u00a0'), "multibyte separator")
+
+	// short integer parts come through String() too
+	assert.Equal(t, "$0.05", NewUSD().SetCents(5).String())
+	assert.Equal(t, "$12.00", NewUSD().SetCents(1200).String())
+}
+
+func TestSetStringOverflowAfterScaling(t *testing.T) {
+	// fits as whole dollars, not as cents
+	_, err := NewUSD().SetString("92233720368547759.00")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = NewUSD().SetString("(92,233,720,368,547,759.00)")
+	assert.Equal(t, ErrOverflow, err)
+
+	// only the cents push it over
+	_, err = NewUSD().SetString("92233720368547758.08")
+	assert.Equal(t, ErrOverflow, err)
+
+	a, err := NewUSD().SetString("92233720368547758.07")
+	assert.Nil(t, err)
+	assert.EqualValues(t, int64(math.MaxInt64), a.Amt)
+
+	// too big before scaling is still ParseInt's error
+	_, err = NewUSD().SetString("99999999999999999999.00")
+	assert.NotNil(t, err)
+}
+
+func TestSetStringBareDecimal(t *testing.T) {
+	cases := map[string]int64{
+		".50":   50,
+		".5":    50,
+		"$.05":  5,
+		"5.":    500,
+		"5":     500,
+		"$5":    500,
+		"1.5":   150,
+		"(.50)": -50,
+		"-5.":   -500,
+	}
+	for in, amt := range cases {
+		a, err := NewUSD().SetString(in)
+		assert.Nil(t, err, in)
+		assert.EqualValues(t, amt, a.Amt, in)
+	}
+
+	for _, in := range []string{".", "$.", "(.)", "", "$"} {
+		_, err := NewUSD().SetString(in)
+		assert.Equal(t, ErrBadString, err, "%q", in)
+	}
+
+	b, err := New(BTC).SetString(".1")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10000000, b.Amt)
+}
+
+func TestSetStringWhitespace(t *testing.T) {
+	for _, in := range []string{" 12.99", "12.99 ", " 12.99 ", "\t$12.99\n"} {
+		a, err := NewUSD().SetString(in)
+		assert.Nil(t, err, "%q", in)
+		assert.EqualValues(t, 1299, a.Amt, "%q", in)
+	}
+	a, err := NewUSD().SetString(" ( $12.99 ) ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1299, a.Amt)
+
+	for _, in := range []string{"", " ", "\t\n", "\u00a0"} {
+		_, err := NewUSD().SetString(in)
+		assert.Equal(t, ErrBadString, err, "%q", in)
+	}
+
+	// space grouping, typed with plain spaces
+	fr, _ := NewUSD().SetLocale(Locales["fr-FR"])
+	a, err = New(*fr).SetString(" 1 234 567,89 $ ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 123456789, a.Amt)
+
+	// but not where spaces aren't grouping
+	_, err = NewUSD().SetString("12 99")
+	assert.NotNil(t, err)
+}
+
+func TestSet(t *testing.T) {
+	x := New(EUR).SetCents(-1234)
+	x.Rational = big.NewRat(-12341, 1000)
+
+	z := NewUSD()
+	p := z
+	assert.True(t, z.Set(x) == p, "same receiver")
+	assert.True(t, z.SameAs(x))
+	assert.Equal(t, "EUR", z.Code)
+	assert.False(t, z.Rational == x.Rational, "deep copy")
+
+	z.Rational.SetInt64(1)
+	assert.Equal(t, 0, x.Rational.Cmp(big.NewRat(-12341, 1000)))
+
+	z.Set(NewUSD().SetCents(5))
+	assert.Nil(t, z.Rational)
+	assert.EqualValues(t, 5, z.Amt)
+	assert.True(t, z.Set(z) == p)
+
+	// reusing the receiver doesn't allocate
+	z.Rational = new(big.Rat)
+	allocs := testing.AllocsPerRun(10, func() { z.Set(x) })
+	assert.EqualValues(t, 0, allocs)
+}
+
+func TestAddSubCents(t *testing.T) {
+	x := NewUSD().SetCents(100)
+	z, err := NewUSD().AddCents(x, 99)
+	assert.Nil(t, err)
+	assert.Equal(t, "$1.99", z.String())
+	assert.True(t, z.isCompatible(x))
+	assert.EqualValues(t, 100, x.Amt)
+
+	z, err = NewUSD().SubCents(x, 199)
+	assert.Nil(t, err)
+	assert.Equal(t, "($0.99)", z.String())
+
+	_, err = x.AddCents(x, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 101, x.Amt, "in place")
+
+	_, err = NewUSD().AddCents(NewUSD().SetCents(math.MaxInt64), 1)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = NewUSD().SubCents(NewUSD().SetCents(math.MinInt64), 1)
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(EUR).AddCents(x, 1)
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func TestDivIntoRatioFrom(t *testing.T) {
+	amts := func(cs []Cash, err error) []int64 {
+		assert.Nil(t, err)
+		ret := make([]int64, len(cs))
+		for i := range cs {
+			ret[i] = cs[i].Amt
+		}
+		return ret
+	}
+	z := NewUSD().SetCents(1000)
+	ratio := []int64{1, 1, 1, 1, 1, 1}
+
+	// 1000/6 = 166 r 4
+	assert.EqualValues(t, []int64{167, 167, 167, 167, 166, 166}, amts(z.DivIntoRatioFrom(ratio, 0)))
+	assert.EqualValues(t, amts(z.DivIntoRatio(ratio)), amts(z.DivIntoRatioFrom(ratio, 0)))
+	assert.EqualValues(t, []int64{166, 166, 167, 167, 167, 167}, amts(z.DivIntoRatioFrom(ratio, 2)))
+	assert.EqualValues(t, []int64{167, 167, 166, 166, 167, 167}, amts(z.DivIntoRatioFrom(ratio, 4)), "wraps")
+	assert.EqualValues(t, amts(z.DivIntoRatioFrom(ratio, 1)), amts(z.DivIntoRatioFrom(ratio, 7)), "start mod len")
+	assert.EqualValues(t, amts(z.DivIntoRatioFrom(ratio, 5)), amts(z.DivIntoRatioFrom(ratio, -1)))
+
+	neg, err := NewUSD().SetCents(-1000).DivIntoRatioFrom(ratio, 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{-167, -166, -166, -167, -167, -167}, amts(neg, nil))
+	var sum int64
+	for _, c := range neg {
+		sum += c.Amt
+	}
+	assert.EqualValues(t, -1000, sum)
+}
+
+func TestDivIntoRatioValidation(t *testing.T) {
+	z := NewUSD().SetCents(1000)
+	for _, ratio := range [][]int64{nil, {}, {0}, {0, 0, 0}, {1, -1}, {3, -1}} {
+		assert.NotPanics(t, func() {
+			res, err := z.DivIntoRatio(ratio)
+			assert.Equal(t, ErrBadRatio, err, "%v", ratio)
+			assert.Nil(t, res)
+		})
+	}
+
+	res, err := z.DivIntoRatio([]int64{7})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res))
+	assert.EqualValues(t, 1000, res[0].Amt, "a single part takes everything")
+
+	res, err = z.DivIntoRatio([]int64{0, 2, 0})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1000, res[1].Amt)
+	assert.EqualValues(t, 0, res[0].Amt+res[2].Amt)
+}
+
+func TestGob(t *testing.T) {
+	type entry struct {
+		Price Cash
+		Fee   *Cash
+	}
+	in := entry{
+		Price: *New(EUR).SetCents(-123456),
+		Fee:   New(BTC).SetCents(1),
+	}
+	in.Fee.Rational = big.NewRat(1, 300000000)
+
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(&in))
+
+	var out entry
+	assert.Nil(t, gob.NewDecoder(&buf).Decode(&out))
+	assert.True(t, out.Price.SameAs(&in.Price))
+	assert.Nil(t, out.Price.Rational)
+	assert.True(t, out.Fee.SameAs(in.Fee))
+	assert.Equal(t, 0, out.Fee.Rational.Cmp(in.Fee.Rational))
+	assert.Equal(t, in.Price.String(), out.Price.String())
+}
+
+func TestPlainString(t *testing.T) {
+	cases := []struct {
+		c    *Cash
+		want string
+	}{
+		{NewUSD().SetCents(-123456), "-1234.56"},
+		{NewUSD().SetCents(123456), "1234.56"},
+		{NewUSD(), "0.00"},
+		{NewUSD().SetCents(5), "0.05"},
+		{NewUSD().SetCents(-5), "-0.05"},
+		{New(BTC).SetCents(1), "0.00000001"},
+		{New(JPY).SetCents(-1000), "-1000"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.c.PlainString())
+		r, ok := new(big.Rat).SetString(c.want)
+		assert.True(t, ok)
+		assert.Equal(t, 0, r.Cmp(c.c.Rat()), "round trip %s", c.want)
+	}
+
+	// display fields don't matter
+	a := New(EUR).SetCents(-123456)
+	a.Decimal, a.Thousands, a.Negative, a.SymbolAfter = ',', '.', NegMinusAfter, true
+	a.ZeroPlaceholder = "—"
+	assert.Equal(t, "-1234.56", a.PlainString())
+	assert.Equal(t, "0.00", a.SetCents(0).PlainString())
+}
+
+func TestStringNoSymbol(t *testing.T) {
+	a := NewUSD().SetCents(1001897)
+	assert.Equal(t, "10,018.97", a.StringNoSymbol())
+	assert.Equal(t, "$10,018.97", a.String(), "z untouched")
+	assert.Equal(t, "(10,018.97)", a.SetCents(-1001897).StringNoSymbol())
+
+	e, _ := New(EUR).SetCents(1000).SetLocale(Locales["de-DE"])
+	assert.Equal(t, "10,00", e.StringNoSymbol())
+}
+
+func TestFormatWith(t *testing.T) {
+	neg := NewUSD().SetCents(-123456)
+	pos := NewUSD().SetCents(123456)
+	cases := []struct {
+		opts FormatOptions
+		pos  string
+		neg  string
+	}{
+		{FormatOptions{}, "$1,234.56", "($1,234.56)"},
+		{FormatOptions{SymbolOutside: true}, "$1,234.56", "$(1,234.56)"},
+		{FormatOptions{Negative: NegMinus}, "$1,234.56", "-$1,234.56"},
+		{FormatOptions{Negative: NegMinus, SymbolOutside: true}, "$1,234.56", "$-1,234.56"},
+		{FormatOptions{Negative: NegMinusAfter}, "$1,234.56", "$1,234.56-"},
+		{FormatOptions{NoSymbol: true}, "1,234.56", "(1,234.56)"},
+		{FormatOptions{NoGrouping: true}, "$1234.56", "($1234.56)"},
+		{FormatOptions{NoSymbol: true, NoGrouping: true, Negative: NegMinus}, "1234.56", "-1234.56"},
+		{FormatOptions{MinFracDigits: 4}, "$1,234.5600", "($1,234.5600)"},
+		{FormatOptions{MinFracDigits: 1}, "$1,234.56", "($1,234.56)"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.pos, pos.FormatWith(c.opts), "%+v", c.opts)
+		assert.Equal(t, c.neg, neg.FormatWith(c.opts), "%+v", c.opts)
+	}
+
+	assert.Equal(t, pos.String(), pos.FormatWith(FormatOptions{}))
+	neg.Negative = NegMinus
+	assert.Equal(t, "-$1,234.56", neg.String(), "String() follows z.Negative")
+
+	e, _ := New(EUR).SetCents(-1000).SetLocale(Locales["de-DE"])
+	assert.Equal(t, "(10,00 €)", e.FormatWith(FormatOptions{}))
+	assert.Equal(t, "(10,00) €", e.FormatWith(FormatOptions{SymbolOutside: true}))
+
+	y := New(JPY).SetCents(1234)
+	assert.Equal(t, "¥1,234.00", y.FormatWith(FormatOptions{MinFracDigits: 2}))
+}
+
+func TestFormatter(t *testing.T) {
+	a := NewUSD().SetCents(-123456)
+	b := NewUSD().SetCents(1299)
+
+	cases := []struct {
+		format string
+		c      *Cash
+		want   string
+	}{
+		{"%s", a, "($1,234.56)"},
+		{"%v", b, "$12.99"},
+		{"%12s|", b, "      $12.99|"},
+		{"%-12v|", b, "$12.99      |"},
+		{"%d", a, "-123456"},
+		{"%8d", b, "    1299"},
+		{"%+d", b, "+1299"},
+		{"%f", a, "-1234.56"},
+		{"%f", b, "12.99"},
+		{"%.0f", b, "13"},
+		{"%.1f", a, "-1234.6"},
+		{"%.4f", b, "12.9900"},
+		{"%10.2f", b, "     12.99"},
+		{"%-10.2f|", b, "12.99     |"},
+		{"%010.2f", a, "-001234.56"},
+		{"%+.2f", b, "+12.99"},
+		{"%q", b, `"$12.99"`},
+		{"%x", b, "2431322e3939"},
+		{"%X", b, "2431322E3939"},
+		{"%e", b, "%!e(cash=$12.99)"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, fmt.Sprintf(c.format, c.c), c.format)
+	}
+
+	// ties follow the rounding mode: 12.5 -> 12 half-even
+	assert.Equal(t, "12", fmt.Sprintf("%.0f", NewUSD().SetCents(1250)))
+	assert.Equal(t, "13", fmt.Sprintf("%.0f", NewUSD().SetRoundingMode(RoundHalfUp).SetCents(1250)))
+	assert.Equal(t, "0.00000000000000000000", fmt.Sprintf("%.20f", NewUSD()), "past the table")
+}
+
+func TestISOCodePrefix(t *testing.T) {
+	opts := FormatOptions{ISOCode: true}
+	cases := []struct {
+		c    *Cash
+		want string
+	}{
+		{NewUSD().SetCents(1234), "USD 12.34"},
+		{NewUSD().SetCents(-123456), "USD (1,234.56)"},
+		{New(EUR).SetCents(1234), "EUR 12.34"},
+		{New(JPY).SetCents(1234), "JPY 1,234"},
+	}
+	for _, c := range cases {
+		s := c.c.FormatWith(opts)
+		assert.Equal(t, c.want, s)
+
+		// parses into whatever preset the code names
+		back, err := NewUSD().SetString(s)
+		assert.Nil(t, err, s)
+		assert.True(t, back.SameAs(c.c), s)
+	}
+
+	assert.Equal(t, "USD -12.34", NewUSD().SetCents(-1234).FormatWith(FormatOptions{ISOCode: true, Negative: NegMinus}))
+	bare := NewUSD().SetCents(1234)
+	bare.Code = ""
+	assert.Equal(t, "$12.34", bare.FormatWith(opts), "no code, keep the symbol")
+
+	_, err := NewUSD().SetString("XYZ 12.34")
+	assert.Equal(t, ErrUnknownCurrency, err)
+
+	// invoices use the other separators too; never 100x off
+	for _, s := range []string{"EUR 12,34", "EUR 12.34"} {
+		e, err := NewUSD().SetString(s)
+		assert.Nil(t, err, s)
+		assert.EqualValues(t, "EUR", e.Code, s)
+		assert.EqualValues(t, 1234, e.Amt, s)
+	}
+	e, err := NewUSD().SetString("(EUR 12,34)")
+	assert.Nil(t, err)
+	assert.EqualValues(t, -1234, e.Amt)
+	e, err = NewUSD().SetString("EUR 1.234,56")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 123456, e.Amt)
+	e, err = NewUSD().SetString("EUR 1,234")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 123400, e.Amt, "reads fine the preset's way")
+}
+
+func TestSetStringGrouping(t *testing.T) {
+	for _, s := range []string{"1,2,3", "12,34", "1,23.00", "1,,234", ",123", "1234,567", "1.23,4"} {
+		_, err := NewUSD().SetString(s)
+		assert.Equal(t, ErrBadString, err, s)
+	}
+	a, err := NewUSD().SetString("1,234,567.89")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 123456789, a.Amt)
+}
+
+func TestKey(t *testing.T) {
+	eu := *NewUSD().SetCents(1299)
+	eu.Decimal, eu.Thousands = ',', '.'
+	eu.Rational = big.NewRat(12991, 1000)
+	amounts := []*Cash{
+		NewUSD().SetCents(1299),
+		New(EUR).SetCents(1299),
+		&eu,
+		NewUSD().SetCents(-1299),
+		New(EUR).SetCents(1299),
+		NewUSD().SetCents(1299),
+	}
+	groups := make(map[string]int)
+	for _, a := range amounts {
+		groups[a.Key()]++
+	}
+	assert.Equal(t, 3, groups["USD 12.99"], "display format and Rational don't matter")
+	assert.Equal(t, 2, groups["EUR 12.99"])
+	assert.Equal(t, 1, groups["USD -12.99"])
+	assert.Equal(t, 3, len(groups))
+
+	noCode := USD
+	noCode.Code = ""
+	assert.Equal(t, "$ 12.99", New(noCode).SetCents(1299).Key())
+	assert.Equal(t, "12.99", (&Cash{Amt: 1299, FracDigits: 2}).Key())
+	assert.NotEqual(t, NewUSD().SetCents(100).Key(), (&Cash{Amt: 10, FracDigits: 1, Code: "USD"}).Key())
+}
+
+func TestEqualsValue(t *testing.T) {
+	us := NewUSD().SetCents(100)
+	eu := NewUSD().SetCents(100)
+	eu.Decimal, eu.Thousands = ',', '.'
+	assert.True(t, us.EqualsValue(eu))
+
+	oneDigit := NewUSD()
+	oneDigit.FracDigits = 1
+	oneDigit.SetCents(10)
+	assert.Equal(t, "$1.0", oneDigit.String())
+	assert.True(t, us.EqualsValue(oneDigit))
+	assert.True(t, oneDigit.EqualsValue(us))
+
+	assert.False(t, us.EqualsValue(NewUSD().SetCents(101)))
+	assert.False(t, us.EqualsValue(New(EUR).SetCents(100)))
+	assert.False(t, New(EUR).SetCents(123).EqualsValue(New(GBP).SetCents(123)))
+}
+
+func TestCompatibleIgnoresSeparators(t *testing.T) {
+	us := NewUSD().SetCents(123456)
+	eu := NewUSD().SetCents(100)
+	eu.Decimal, eu.Thousands = ',', '.'
+	sp := NewUSD().SetCents(1)
+	sp.Thousands = ' '
+
+	z, err := NewUSD().Add(us, eu)
+	assert.Nil(t, err)
+	assert.Equal(t, "$1,235.56", z.String(), "result keeps the receiver's format")
+
+	z, err = New(*eu).Sub(us, sp)
+	assert.Nil(t, err)
+	assert.Equal(t, "$1.234,55", z.String())
+
+	is, err := us.Equals(eu)
+	assert.Nil(t, err)
+	assert.False(t, is)
+
+	// still not across currencies or precisions
+	_, err = NewUSD().Add(us, New(EUR))
+	assert.Equal(t, ErrIncompatible, err)
+	_, err = NewUSD().Add(us, New(BTC))
+	assert.Equal(t, ErrIncompatible, err)
+
+	// SameAs still sees the separators
+	assert.False(t, NewUSD().SetCents(100).SameAs(eu))
+}
+
+func TestRescale(t *testing.T) {
+	rate := NewUSD()
+	rate.FracDigits = 4
+	rate.SetCents(12345)
+	assert.Equal(t, "$1.2345", rate.String())
+
+	z, err := NewUSD().Rescale(rate, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "$1.23", z.String())
+	assert.EqualValues(t, 12345, rate.Amt, "x untouched")
+	assert.EqualValues(t, 4, rate.FracDigits)
+
+	up, err := NewUSD().Rescale(NewUSD().SetCents(123), 4)
+	assert.Nil(t, err)
+	assert.Equal(t, "$1.2300", up.String())
+
+	// normalize, then add
+	sum, err := NewUSD().Add(z, NewUSD().SetCents(100))
+	assert.Nil(t, err)
+	assert.Equal(t, "$2.23", sum.String())
+
+	// the receiver's rounding mode applies
+	half := NewUSD()
+	half.FracDigits = 3
+	half.SetCents(1225)
+	z, _ = NewUSD().Rescale(half, 2)
+	assert.EqualValues(t, 122, z.Amt)
+	z, _ = NewUSD().SetRoundingMode(RoundHalfUp).Rescale(half, 2)
+	assert.EqualValues(t, 123, z.Amt)
+
+	z = NewUSD().SetCents(7)
+	_, err = z.Rescale(rate, 19)
+	assert.True(t, errors.Is(err, ErrBadScale))
+	assert.EqualValues(t, 7, z.Amt, "z untouched on error")
+}
+
+func TestAllocate(t *testing.T) {
+	amts := func(cs []Cash) []int64 {
+		ret := make([]int64, len(cs))
+		for i := range cs {
+			ret[i] = cs[i].Amt
+		}
+		return ret
+	}
+	usd := func(cents int64) *Cash { return NewUSD().SetCents(cents) }
+	refund := usd(10000)
+
+	// no caps: same as a plain ratio split
+	shares, rem, err := refund.Allocate([]int64{1, 1, 1}, nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{3334, 3333, 3333}, amts(shares))
+	assert.EqualValues(t, 0, rem.Amt)
+
+	// the first card only has room for $10; the rest spills over
+	shares, rem, err = refund.Allocate([]int64{1, 1, 1}, []*Cash{usd(1000), nil, usd(5000)})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{1000, 4500, 4500}, amts(shares))
+	assert.EqualValues(t, 0, rem.Amt)
+
+	// spill-over fills the next cap, which spills again
+	shares, rem, err = refund.Allocate([]int64{2, 1, 1}, []*Cash{usd(2000), usd(3000), nil})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{2000, 3000, 5000}, amts(shares))
+	assert.EqualValues(t, 0, rem.Amt)
+
+	// not enough room anywhere
+	shares, rem, err = refund.Allocate([]int64{1, 1}, []*Cash{usd(2500), usd(4000)})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{2500, 4000}, amts(shares))
+	assert.EqualValues(t, 3500, rem.Amt)
+	assert.Equal(t, "$35.00", rem.String())
+
+	// zero weight never receives
+	shares, _, err = refund.Allocate([]int64{0, 1}, []*Cash{nil, usd(100)})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int64{0, 100}, amts(shares))
+
+	// the total is always conserved
+	for _, total := range []int64{0, 1, 7, 9999, 123457} {
+		shares, rem, err := usd(total).Allocate([]int64{3, 5, 2, 1}, []*Cash{usd(11), nil, usd(0), usd(4000)})
+		assert.Nil(t, err)
+		var sum int64
+		for _, s := range shares {
+			sum += s.Amt
+		}
+		assert.EqualValues(t, total, sum+rem.Amt, "%d", total)
+	}
+
+	_, _, err = refund.Allocate(nil, nil)
+	assert.Equal(t, ErrBadRatio, err)
+	_, _, err = refund.Allocate([]int64{1, 1}, []*Cash{nil})
+	assert.True(t, errors.Is(err, ErrBadCap))
+	_, _, err = refund.Allocate([]int64{1}, []*Cash{usd(-1)})
+	assert.Equal(t, ErrBadCap, err)
+	_, _, err = refund.Allocate([]int64{1}, []*Cash{New(EUR)})
+	assert.Equal(t, ErrIncompatible, err)
+	_, _, err = usd(-100).Allocate([]int64{1}, nil)
+	assert.True(t, errors.Is(err, ErrNegativeAmount))
+}
+
+func TestExactThenAddClearsRational(t *testing.T) {
+	e, err := NewUSD().SetExactMode(true).MulByRat(NewUSD().SetCents(1000), big.NewRat(1, 3))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 333, e.Amt)
+	assert.NotNil(t, e.Rational)
+
+	_, err = e.Add(e, NewUSD().SetCents(500))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 833, e.Amt)
+	assert.Nil(t, e.Rational, "Add wrote Amt, so the old exact value is gone")
+	assert.EqualValues(t, 833, e.Settle().Amt)
+
+	// the other plain-Amt writers too
+	writers := map[string]func(c *Cash){
+		"Sub":         func(c *Cash) { c.Sub(c, NewUSD()) },
+		"SetCents":    func(c *Cash) { c.SetCents(7) },
+		"MulByScalar": func(c *Cash) { c.MulByScalar(c, 2) },
+		"MulByCash":   func(c *Cash) { c.MulByCash(c, NewUSD().SetCents(100)) },
+		"AddCents":    func(c *Cash) { c.AddCents(c, 1) },
+		"SetString":   func(c *Cash) { c.SetString("$1.23") },
+		"AbsDiff":     func(c *Cash) { c.AbsDiff(c, NewUSD()) },
+	}
+	for name, w := range writers {
+		c, _ := NewUSD().SetExactMode(true).MulByRat(NewUSD().SetCents(1000), big.NewRat(1, 3))
+		w(c)
+		assert.Nil(t, c.Rational, name)
+	}
+}
+
+func TestSetPrecIgnoresStaleRational(t *testing.T) {
+	a := NewUSD().SetCents(833)
+	a.Rational = big.NewRat(10, 3) // left over, doesn't round to 833
+	_, err := a.SetPrec(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 83300, a.Amt)
+
+	r, err := NewUSD().Rescale(&Cash{Amt: 833, FracDigits: 2, Currency: '$', Code: "USD", Rational: big.NewRat(10, 3)}, 2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 833, r.Amt)
+
+	// a matching one is still used for the extra digits
+	b := NewUSD().SetCents(333)
+	b.Rational = big.NewRat(10, 3)
+	b.SetPrec(4)
+	assert.EqualValues(t, 33333, b.Amt)
+}
+
+func TestSerializeIgnoresDisplayFrac(t *testing.T) {
+	a := NewUSD()
+	a.FracDigits = 4
+	a.SetCents(12345)
+	a.MaxFracDigits = 2
+	assert.Equal(t, "$1.23", a.String(), "display still trims")
+
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+	assert.Equal(t, `"$1.2345"`, string(b))
+	back := New(*a)
+	back.Amt = 0
+	assert.Nil(t, json.Unmarshal(b, back))
+	assert.EqualValues(t, 12345, back.Amt)
+
+	txt, _ := a.MarshalText()
+	assert.Equal(t, "$1.2345", string(txt))
+	v, _ := a.Value()
+	assert.Equal(t, "$1.2345", v)
+}
+
+func TestZeroPlaceholderRoundTrip(t *testing.T) {
+	a := NewUSD()
+	a.ZeroPlaceholder = "—"
+	assert.Equal(t, "—", a.String())
+
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+	assert.Equal(t, `"$0.00"`, string(b))
+	back := New(*a).SetCents(5)
+	assert.Nil(t, json.Unmarshal(b, back))
+	assert.EqualValues(t, 0, back.Amt)
+
+	v, err := a.Value()
+	assert.Nil(t, err)
+	scanned := New(*a).SetCents(5)
+	assert.Nil(t, scanned.Scan(v))
+	assert.EqualValues(t, 0, scanned.Amt)
+
+	// the placeholder itself parses as zero for the receiver that uses it
+	p, err := New(*a).SetCents(5).SetString(" — ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, p.Amt)
+	assert.Nil(t, a.VerifyRoundTrip([]int64{0, 1, -1}))
+}
+
+func TestSplitWithCapsNegative(t *testing.T) {
+	_, err := NewUSD().SetCents(-100).SplitWithCaps([]int64{-1, -1, -1})
+	assert.True(t, errors.Is(err, ErrNegativeAmount))
+
+	shares, err := NewUSD().SplitWithCaps([]int64{-1, 5})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, shares[0].Amt+shares[1].Amt)
+}
+
+func TestSetStringFailureLeavesReceiver(t *testing.T) {
+	a := NewUSD().SetCents(7)
+	_, err := a.SetString("EUR abc")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 7, a.Amt)
+	assert.EqualValues(t, "USD", a.Code)
+	assert.EqualValues(t, "$0.07", a.String())
+
+	_, err = a.SetString("EUR 99999999999999999999")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "$0.07", a.String())
+
+	_, err = a.SetString("EUR 1,50")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "EUR", a.Code, "success still switches")
+	assert.EqualValues(t, 150, a.Amt)
+	assert.EqualValues(t, "€1.50", a.String())
+}
+
+func TestDisplayFracHugeMin(t *testing.T) {
+	a := NewUSD().SetCents(150)
+	a.MinFracDigits, a.MaxFracDigits = 30, 2
+	assert.NotPanics(t, func() { _ = a.String() })
+	assert.EqualValues(t, "$1.50", a.String(), "too many to pad")
+
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `"$1.50"`, string(b))
+	v, err := a.Value()
+	assert.Nil(t, err)
+	assert.EqualValues(t, "$1.50", v)
+}